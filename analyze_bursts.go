@@ -0,0 +1,208 @@
+package gobdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// The error returned when no Burst contains a range of TransactionIds.
+type ErrBurstGap struct {
+	First, Last TransactionId
+}
+
+func (e *ErrBurstGap) Error() string {
+	return fmt.Sprintf("gobdb: no Burst contains the Transactions from %d to %d", e.First, e.Last)
+}
+
+// The error returned when two Bursts contain different Writers for the same
+// TransactionId.
+type ErrBurstConflict struct {
+	Id   TransactionId
+	A, B BurstId
+}
+
+func (e *ErrBurstConflict) Error() string {
+	return fmt.Sprintf("gobdb: Bursts %d-%d and %d-%d contain different Transactions %d",
+		e.A.First(), e.A.Last(), e.B.First(), e.B.Last(), e.Id)
+}
+
+// The result of AnalyzeBursts().
+type BurstAnalysis struct {
+	// The ranges of missing TransactionIds, in ascending order.
+	Gaps []ErrBurstGap
+	// The Bursts whose Transactions are all contained in the other Bursts
+	// that are not redundant.
+	Redundant []BurstId
+	// The Transactions whose Writers have different gob encodings in two
+	// Bursts, before the Migrations if the BurstReaders implement
+	// rawBurstReader. Maps are encoded in random order, so Writers with
+	// maps of several entries may be reported too.
+	Conflicts []ErrBurstConflict
+}
+
+// It returns the first gap or conflict, if any.
+func (a *BurstAnalysis) Err() error {
+	if len(a.Gaps) > 0 {
+		return &a.Gaps[0]
+	}
+	if len(a.Conflicts) > 0 {
+		return &a.Conflicts[0]
+	}
+	return nil
+}
+
+// It reads all the Transactions of the Bursts after the given TransactionId
+// and looks for gaps, redundant Bursts and conflicts. Only the Writers of
+// Bursts whose ranges overlap are kept in memory at the same time.
+func AnalyzeBursts(lastId TransactionId, burstIds []BurstId) (*BurstAnalysis, error) {
+
+	ids := make([]BurstId, 0, len(burstIds))
+	for _, id := range burstIds {
+		if id.Last() > lastId {
+			ids = append(ids, id)
+		}
+	}
+	SortBursts(ids)
+
+	analysis := &BurstAnalysis{}
+	next := lastId + 1
+	for i := 0; i < len(ids); {
+		j, last := i+1, ids[i].Last()
+		for ; j < len(ids) && ids[j].First() <= last; j++ {
+			if ids[j].Last() > last {
+				last = ids[j].Last()
+			}
+		}
+		var err error
+		if next, err = analysis.analyze(lastId, next, ids[i:j]); err != nil {
+			return nil, err
+		}
+		i = j
+	}
+	return analysis, nil
+}
+
+type analyzedTransaction struct {
+	writer []byte
+	burst  BurstId
+	count  int
+}
+
+// An optional interface of BurstReaders that can read the Transactions as they
+// were written, without applying the Migrations.
+type rawBurstReader interface {
+	readRaw() (Transaction, error)
+}
+
+// It reads the next Transaction of a BurstReader without applying the
+// Migrations if possible, and returns the gob encoding of its Writer.
+func readEncodedTransaction(reader BurstReader) (TransactionId, []byte, error) {
+	var transaction Transaction
+	var err error
+	if raw, ok := reader.(rawBurstReader); ok {
+		transaction, err = raw.readRaw()
+	} else {
+		transaction, err = reader.Read()
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(&transaction.Writer); err != nil {
+		return 0, nil, err
+	}
+	return transaction.Id, buffer.Bytes(), nil
+}
+
+// It analyzes a group of Bursts whose ranges overlap and returns the next
+// expected TransactionId.
+func (a *BurstAnalysis) analyze(lastId, next TransactionId, ids []BurstId) (TransactionId, error) {
+
+	transactions := make(map[TransactionId]*analyzedTransaction)
+	contents := make([][]TransactionId, len(ids))
+	for k, id := range ids {
+		reader, err := id.Read()
+		if err != nil {
+			return next, err
+		}
+		for {
+			transactionId, writer, err := readEncodedTransaction(reader)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				reader.Close()
+				return next, err
+			}
+			if transactionId <= lastId {
+				continue
+			}
+			contents[k] = append(contents[k], transactionId)
+			if t, ok := transactions[transactionId]; ok {
+				t.count++
+				if !bytes.Equal(t.writer, writer) {
+					a.Conflicts = append(a.Conflicts, ErrBurstConflict{transactionId, t.burst, id})
+				}
+			} else {
+				transactions[transactionId] = &analyzedTransaction{writer, id, 1}
+			}
+		}
+		if err := reader.Close(); err != nil {
+			return next, err
+		}
+	}
+
+	sorted := make([]TransactionId, 0, len(transactions))
+	for id := range transactions {
+		sorted = append(sorted, id)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for _, id := range sorted {
+		if id > next {
+			a.Gaps = append(a.Gaps, ErrBurstGap{next, id - 1})
+		}
+		next = id + 1
+	}
+
+	order := make([]int, len(ids))
+	for k := range order {
+		order[k] = k
+	}
+	sort.SliceStable(order, func(i, j int) bool { return len(contents[order[i]]) < len(contents[order[j]]) })
+	for _, k := range order {
+		redundant := true
+		for _, id := range contents[k] {
+			if transactions[id].count < 2 {
+				redundant = false
+				break
+			}
+		}
+		if redundant {
+			a.Redundant = append(a.Redundant, ids[k])
+			for _, id := range contents[k] {
+				transactions[id].count--
+			}
+		}
+	}
+
+	return next, nil
+}
+
+// Like ApplyBurstsContext(), but it first reads the Bursts with
+// AnalyzeBursts() and it returns an *ErrBurstGap or an *ErrBurstConflict
+// instead of applying them if they have any gap or conflict.
+func ApplyBurstsChecked(ctx context.Context, root Root, lastId TransactionId, nextLastId *TransactionId, burstIds []BurstId, progress func(Progress)) error {
+	*nextLastId = lastId
+	analysis, err := AnalyzeBursts(lastId, burstIds)
+	if err != nil {
+		return err
+	}
+	if err := analysis.Err(); err != nil {
+		return err
+	}
+	return ApplyBurstsContext(ctx, root, lastId, nextLastId, burstIds, progress)
+}
@@ -0,0 +1,212 @@
+package gobdb
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestAnalyzeBursts(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 3)
+	testWriteBurst(t, repository, 2, 3, 4, 6)
+	testWriteBurst(t, repository, 6)
+	testWriteBurst(t, repository, 9, 10)
+
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	analysis, err := AnalyzeBursts(0, bursts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(analysis.Gaps) != 2 {
+		t.Error(analysis.Gaps)
+	} else {
+		if analysis.Gaps[0] != (ErrBurstGap{5, 5}) {
+			t.Error(analysis.Gaps[0])
+		}
+		if analysis.Gaps[1] != (ErrBurstGap{7, 8}) {
+			t.Error(analysis.Gaps[1])
+		}
+	}
+	if len(analysis.Redundant) != 1 {
+		t.Error(analysis.Redundant)
+	} else if id := analysis.Redundant[0]; id.First() != 6 || id.Last() != 6 {
+		t.Error(id)
+	}
+	if len(analysis.Conflicts) != 0 {
+		t.Error(analysis.Conflicts)
+	}
+	if err, ok := analysis.Err().(*ErrBurstGap); !ok || err.First != 5 {
+		t.Error(err)
+	}
+
+	analysis, err = AnalyzeBursts(6, bursts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Gaps) != 1 || analysis.Gaps[0] != (ErrBurstGap{7, 8}) {
+		t.Error(analysis.Gaps)
+	}
+	if len(analysis.Redundant) != 0 {
+		t.Error(analysis.Redundant)
+	}
+}
+
+func TestAnalyzeBurstsConflict(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 2, 3)
+
+	wburst, err := repository.WriteBurst()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wburst.Write(Transaction{2, &testWriter{-12}}); err != nil {
+		t.Error(err)
+	}
+	if err := wburst.Close(); err != nil {
+		t.Error(err)
+	}
+
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	analysis, err := AnalyzeBursts(0, bursts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Gaps) != 0 {
+		t.Error(analysis.Gaps)
+	}
+	if len(analysis.Conflicts) != 1 {
+		t.Error(analysis.Conflicts)
+	} else if c := analysis.Conflicts[0]; c.Id != 2 || c.A.Last() != 3 || c.B.Last() != 2 {
+		t.Error(c)
+	}
+
+	root := &testRoot{}
+	var id TransactionId
+	err = ApplyBurstsChecked(context.Background(), root, 0, &id, bursts, nil)
+	if c, ok := err.(*ErrBurstConflict); !ok || c.Id != 2 {
+		t.Error(err)
+	}
+	if id != 0 {
+		t.Error(id)
+	}
+	if root.counter != 0 {
+		t.Error(root.counter)
+	}
+}
+
+func TestApplyBurstsChecked(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 2)
+	testWriteBurst(t, repository, 4)
+
+	root := &testRoot{}
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	var id TransactionId
+	err = ApplyBurstsChecked(context.Background(), root, 0, &id, bursts, nil)
+	if gap, ok := err.(*ErrBurstGap); !ok || gap.First != 3 || gap.Last != 3 {
+		t.Error(err)
+	}
+
+	testWriteBurst(t, repository, 3)
+	bursts, err = repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	if err := ApplyBurstsChecked(context.Background(), root, 0, &id, bursts, nil); err != nil {
+		t.Error(err)
+	}
+	if id != 4 {
+		t.Error(id)
+	}
+	if root.counter != 50 {
+		t.Error(root.counter)
+	}
+}
+
+// A Writer whose values are reflect.DeepEqual() to themselves only sometimes.
+type testVectorWriter struct {
+	Values []float64
+}
+
+func (op *testVectorWriter) Write(root Root) (interface{}, error) {
+	return nil, nil
+}
+
+func init() {
+	RegisterWriter(&testVectorWriter{})
+}
+
+func TestAnalyzeBurstsEncodedWriters(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	for _, values := range [][]float64{nil, {}} {
+		wburst, err := repository.WriteBurst()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := wburst.Write(Transaction{1, &testVectorWriter{values}}); err != nil {
+			t.Error(err)
+		}
+		if err := wburst.Write(Transaction{2, &testVectorWriter{[]float64{math.NaN()}}}); err != nil {
+			t.Error(err)
+		}
+		if err := wburst.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	analysis, err := AnalyzeBursts(0, bursts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Conflicts) != 0 {
+		t.Error(analysis.Conflicts)
+	}
+}
+
+func TestAnalyzeBurstsMigratedConflict(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	for _, writer := range []Writer{&testWriter{11}, &testWriterV0{11}} {
+		wburst, err := repository.WriteBurst()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := wburst.Write(Transaction{1, writer}); err != nil {
+			t.Error(err)
+		}
+		if err := wburst.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	analysis, err := AnalyzeBursts(0, bursts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Conflicts) != 1 {
+		t.Error(analysis.Conflicts)
+	}
+}
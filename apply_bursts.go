@@ -1,6 +1,7 @@
 package gobdb
 
 import (
+	"context"
 	"io"
 )
 
@@ -9,15 +10,70 @@ type applyBurstsReader struct {
 	BurstReader
 }
 
+// An optional interface of BurstIds that are told when ApplyBursts() discards
+// them without reading them, because they are redundant.
+type burstSkipper interface {
+	skip()
+}
+
+// It opens a Burst, skipping the Transactions before the given one if the
+// BurstId implements SeekBurstId.
+func readBurst(id BurstId, next TransactionId) (BurstReader, error) {
+	if seek, ok := id.(SeekBurstId); ok && id.First() < next {
+		return seek.ReadFrom(next)
+	}
+	return id.Read()
+}
+
 // Applies bursts in order to a Root object. It receives and returns the last
 // TransactionId applied to the Root. It sorts the []BurstId with SortBursts().
-func ApplyBursts(root Root, lastId TransactionId, nextLastId *TransactionId, burstIds []BurstId) (err error) {
+// The error of a Writer is ignored if the next Transaction is its
+// AbortedTransaction, and the returned TransactionId moves past both of them.
+// Otherwise it returns the error and the TransactionId before that Writer,
+// even if the Writer modified the Root before failing.
+func ApplyBursts(root Root, lastId TransactionId, nextLastId *TransactionId, burstIds []BurstId) error {
+	return ApplyBurstsContext(context.Background(), root, lastId, nextLastId, burstIds, nil)
+}
+
+// Like ApplyBursts(), but it stops with the error of the Context as soon as
+// it is cancelled, closing the open BurstReaders, and it invokes the optional
+// function after every opened Burst and applied Transaction.
+// The returned TransactionId is valid even if it fails, so it may be resumed.
+func ApplyBurstsContext(ctx context.Context, root Root, lastId TransactionId, nextLastId *TransactionId, burstIds []BurstId, progress func(Progress)) (err error) {
 
 	last, next := lastId, lastId+1
 	SortBursts(burstIds)
 
+	status := Progress{Target: lastId}
+	for _, id := range burstIds {
+		if id.Last() > status.Target {
+			status.Target = id.Last()
+		}
+	}
+
+	// the error of the last Writer, until its AbortedTransaction
+	var aborted error
+	var abortedLast TransactionId
+
 	readers := []applyBurstsReader{}
+	var closedBytes int64
+	report := func() {
+		if progress == nil {
+			return
+		}
+		status.Id, status.Bytes = last, closedBytes
+		for _, r := range readers {
+			status.Bytes += bytesRead(r.BurstReader)
+		}
+		progress(status)
+	}
 	defer func() {
+		if aborted != nil {
+			last = abortedLast
+			if err == nil {
+				err = aborted
+			}
+		}
 		*nextLastId = last
 		for _, r := range readers {
 			r.Close()
@@ -26,6 +82,10 @@ func ApplyBursts(root Root, lastId TransactionId, nextLastId *TransactionId, bur
 
 	for {
 
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
 		var (
 			index       int
 			transaction *Transaction
@@ -38,6 +98,7 @@ func ApplyBursts(root Root, lastId TransactionId, nextLastId *TransactionId, bur
 						return
 					}
 					err = nil
+					closedBytes += bytesRead(readers[index].BurstReader)
 					lastIndex := len(readers) - 1
 					if index < lastIndex {
 						readers[index], readers[lastIndex] = readers[lastIndex], applyBurstsReader{}
@@ -57,10 +118,17 @@ func ApplyBursts(root Root, lastId TransactionId, nextLastId *TransactionId, bur
 		}
 
 		if transaction != nil {
+			if aborted != nil {
+				if op, ok := transaction.Writer.(*AbortedTransaction); !ok || op.Id != last {
+					return
+				}
+				aborted = nil
+			}
 			if _, err = transaction.Write(root); err != nil {
-				return
+				aborted, abortedLast, err = err, last, nil
 			}
 			last, next = next, next+1
+			report()
 			continue
 		}
 
@@ -71,15 +139,19 @@ func ApplyBursts(root Root, lastId TransactionId, nextLastId *TransactionId, bur
 			open = id.First() <= next && !skip
 			if open {
 				var reader applyBurstsReader
-				if reader.BurstReader, err = id.Read(); err != nil {
+				if reader.BurstReader, err = readBurst(id, next); err != nil {
 					return
 				}
 				readers = append(readers, reader)
+				status.Opened++
+				report()
 				skip = true
+			} else if skipper, ok := id.(burstSkipper); ok && skip {
+				skipper.skip()
 			}
 			if skip {
 				lastIndex := len(burstIds) - 1
-				if index < lastIndex {
+				if i < lastIndex {
 					burstIds[i], burstIds[lastIndex] = burstIds[lastIndex], nil
 				}
 				burstIds = burstIds[:lastIndex]
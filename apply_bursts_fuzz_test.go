@@ -0,0 +1,19 @@
+//go:build go1.18
+// +build go1.18
+
+package gobdb
+
+import (
+	"testing"
+)
+
+func FuzzApplyBursts(f *testing.F) {
+	f.Add([]byte{0, 0, 3, 0})
+	f.Add([]byte{0, 0, 3, 0, 2, 3, 0, 1, 2, 0})
+	f.Add([]byte{2, 0, 7, 2, 1, 4, 0, 5, 3, 0})
+	f.Add([]byte{0, 0, 5, 4, 2, 1, 0, 0, 5, 0, 10, 2, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		lastId, bursts := testDecodeBursts(data)
+		testApplyBurstsProperty(t, lastId, bursts)
+	})
+}
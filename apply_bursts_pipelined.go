@@ -0,0 +1,180 @@
+package gobdb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Like ApplyBurstsContext(), but the Bursts are opened and decoded by other
+// goroutines into buffers of the given number of Transactions, while the
+// calling goroutine still applies them in order.
+// Besides the Bursts being applied, up to the given number of workers decode
+// the next ones ahead in the order of SortBursts(), at least one. A Burst
+// decoded ahead that turns out to be redundant is stopped, so that its worker
+// moves to the next one.
+func ApplyBurstsPipelined(ctx context.Context, root Root, lastId TransactionId, nextLastId *TransactionId, burstIds []BurstId, workers, buffer int, progress func(Progress)) error {
+
+	if workers < 1 {
+		*nextLastId = lastId
+		return errors.New("gobdb: ApplyBurstsPipelined() with less than one worker")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+
+	SortBursts(burstIds)
+	prefetched := make([]*prefetchBurstId, 0, len(burstIds))
+	ids := make([]BurstId, 0, len(burstIds))
+	for _, id := range burstIds {
+		if id.Last() > lastId {
+			pid := &prefetchBurstId{BurstId: id, ctx: ctx, buffer: buffer, wg: &wg}
+			prefetched = append(prefetched, pid)
+			ids = append(ids, pid)
+		}
+	}
+
+	tokens := make(chan struct{}, workers)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, id := range prefetched {
+			select {
+			case tokens <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			id.start(func() { <-tokens })
+		}
+	}()
+
+	err := ApplyBurstsContext(ctx, root, lastId, nextLastId, ids, progress)
+	cancel()
+	wg.Wait()
+	return err
+}
+
+// A BurstId whose Transactions are decoded by another goroutine.
+type prefetchBurstId struct {
+	BurstId
+	ctx    context.Context
+	buffer int
+	wg     *sync.WaitGroup
+	once   sync.Once
+	reader *prefetchBurstReader
+}
+
+// It starts the decoding goroutine if it was not started yet. The function is
+// invoked once the decoding finishes, or immediately if it was already started.
+func (id *prefetchBurstId) start(release func()) {
+	started := false
+	id.once.Do(func() {
+		started = true
+		ctx, cancel := context.WithCancel(id.ctx)
+		id.reader = &prefetchBurstReader{
+			id:     id.BurstId,
+			items:  make(chan prefetchItem, id.buffer),
+			cancel: cancel,
+			done:   make(chan struct{}),
+		}
+		id.wg.Add(1)
+		go func() {
+			defer id.wg.Done()
+			defer release()
+			id.reader.decode(ctx)
+		}()
+	})
+	if !started {
+		release()
+	}
+}
+
+func (id *prefetchBurstId) Read() (BurstReader, error) {
+	id.start(func() {})
+	return id.reader, nil
+}
+
+// Implements burstSkipper.skip(). It prevents the decoding goroutine from
+// starting, or it stops it.
+func (id *prefetchBurstId) skip() {
+	id.once.Do(func() {})
+	if id.reader != nil {
+		id.reader.cancel()
+	}
+}
+
+type prefetchItem struct {
+	Transaction
+	err error
+}
+
+type prefetchBurstReader struct {
+	id     BurstId
+	items  chan prefetchItem
+	cancel context.CancelFunc
+	done   chan struct{}
+	bytes  int64
+}
+
+// It decodes the Transactions of the Burst until io.EOF, an error or the
+// cancellation of the Context.
+func (br *prefetchBurstReader) decode(ctx context.Context) {
+	defer close(br.done)
+	defer close(br.items)
+	reader, err := br.id.Read()
+	if err != nil {
+		br.send(ctx, prefetchItem{err: err})
+		return
+	}
+	for {
+		transaction, err := reader.Read()
+		atomic.StoreInt64(&br.bytes, bytesRead(reader))
+		if err != nil {
+			if err2 := reader.Close(); err == io.EOF && err2 != nil {
+				err = err2
+			}
+			if err != io.EOF {
+				br.send(ctx, prefetchItem{err: err})
+			}
+			return
+		}
+		if !br.send(ctx, prefetchItem{transaction, nil}) {
+			reader.Close()
+			return
+		}
+	}
+}
+
+func (br *prefetchBurstReader) send(ctx context.Context, item prefetchItem) bool {
+	select {
+	case br.items <- item:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (br *prefetchBurstReader) Id() BurstId {
+	return br.id
+}
+
+func (br *prefetchBurstReader) Read() (Transaction, error) {
+	item, ok := <-br.items
+	if !ok {
+		return Transaction{}, io.EOF
+	}
+	return item.Transaction, item.err
+}
+
+func (br *prefetchBurstReader) BytesRead() int64 {
+	return atomic.LoadInt64(&br.bytes)
+}
+
+func (br *prefetchBurstReader) Close() error {
+	br.cancel()
+	<-br.done
+	return nil
+}
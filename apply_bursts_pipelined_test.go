@@ -0,0 +1,262 @@
+package gobdb
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestApplyBurstsPipelined(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 3)
+	testWriteBurst(t, repository, 2, 3, 4, 6)
+	testWriteBurst(t, repository, 6)
+	testWriteBurst(t, repository, 5, 6, 7)
+
+	for workers := 1; workers < 4; workers++ {
+		for buffer := 0; buffer < 3; buffer++ {
+			root := &testRoot{0}
+			bursts, err := repository.Bursts()
+			if err != nil {
+				t.Error(err)
+			}
+			var id TransactionId
+			if err := ApplyBurstsPipelined(context.Background(), root, 0, &id, bursts, workers, buffer, nil); err != nil {
+				t.Error(workers, buffer, err)
+			}
+			if id != 7 {
+				t.Error(workers, buffer, id)
+			}
+			if root.counter != 98 {
+				t.Error(workers, buffer, root.counter)
+			}
+		}
+	}
+}
+
+func TestApplyBurstsPipelinedCancel(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 2, 3, 4)
+	testWriteBurst(t, repository, 5, 6)
+
+	root := &testRoot{0}
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	progress := func(p Progress) {
+		if p.Id == 2 {
+			cancel()
+		}
+	}
+	var id TransactionId
+	if err := ApplyBurstsPipelined(ctx, root, 0, &id, bursts, 2, 1, progress); err != context.Canceled {
+		t.Error(err)
+	}
+	if id != 2 {
+		t.Error(id)
+	}
+	if root.counter != 23 {
+		t.Error(root.counter)
+	}
+}
+
+func TestApplyBurstsPipelinedNoWorkers(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 2)
+
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	id := TransactionId(1)
+	if err := ApplyBurstsPipelined(context.Background(), &testRoot{}, 0, &id, bursts, 0, 1, nil); err == nil {
+		t.Error(err)
+	}
+	if id != 0 {
+		t.Error(id)
+	}
+}
+
+// A BurstId that records whether ApplyBursts() has skipped it.
+type testSkipBurstId struct {
+	BurstId
+	skipped bool
+}
+
+func (id *testSkipBurstId) skip() {
+	id.skipped = true
+}
+
+func TestApplyBurstsSkipsRedundant(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 2, 3)
+	testWriteBurst(t, repository, 2)
+	testWriteBurst(t, repository, 4)
+
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	ids := make([]*testSkipBurstId, len(bursts))
+	for i, id := range bursts {
+		ids[i] = &testSkipBurstId{BurstId: id}
+		bursts[i] = ids[i]
+	}
+	var id TransactionId
+	if err := ApplyBursts(&testRoot{}, 0, &id, bursts); err != nil {
+		t.Error(err)
+	}
+	if id != 4 {
+		t.Error(id)
+	}
+	for _, id := range ids {
+		if id.skipped != (id.First() == 2) {
+			t.Error(id.First(), id.skipped)
+		}
+	}
+}
+
+func TestPrefetchBurstIdSkip(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 2, 3)
+	testWriteBurst(t, repository, 4)
+
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	SortBursts(bursts)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// skipped while it is blocked on its buffer
+	released := make(chan struct{})
+	id := &prefetchBurstId{BurstId: bursts[0], ctx: context.Background(), buffer: 0, wg: &wg}
+	id.start(func() { close(released) })
+	id.skip()
+	select {
+	case <-released:
+	case <-time.After(10 * time.Second):
+		t.Error("not released")
+	}
+
+	// skipped before it is started
+	released = make(chan struct{})
+	id = &prefetchBurstId{BurstId: bursts[1], ctx: context.Background(), buffer: 0, wg: &wg}
+	id.skip()
+	id.start(func() { close(released) })
+	select {
+	case <-released:
+	default:
+		t.Error("not released")
+	}
+}
+
+// A BurstId whose BurstReaders take the given time to be opened and to read
+// every 100 Transactions, like a disk.
+type testLatencyBurstId struct {
+	BurstId
+	latency time.Duration
+}
+
+func (id *testLatencyBurstId) Read() (BurstReader, error) {
+	time.Sleep(id.latency)
+	reader, err := id.BurstId.Read()
+	if err != nil {
+		return nil, err
+	}
+	return &testLatencyBurstReader{reader, id.latency, 0}, nil
+}
+
+type testLatencyBurstReader struct {
+	BurstReader
+	latency time.Duration
+	count   int
+}
+
+func (br *testLatencyBurstReader) Read() (Transaction, error) {
+	if br.count++; br.count%100 == 0 {
+		time.Sleep(br.latency)
+	}
+	return br.BurstReader.Read()
+}
+
+func benchmarkApplyBursts(b *testing.B, apply func(Root, *TransactionId, []BurstId) error) {
+	benchmarkApplyBurstsLatency(b, 0, apply)
+}
+
+func benchmarkApplyBurstsLatency(b *testing.B, latency time.Duration, apply func(Root, *TransactionId, []BurstId) error) {
+
+	repository := NewMemBurstRepository()
+	var id TransactionId
+	for i := 0; i < 16; i++ {
+		wburst, err := repository.WriteBurst()
+		if err != nil {
+			b.Fatal(err)
+		}
+		for j := 0; j < 2000; j++ {
+			id++
+			if err := wburst.Write(Transaction{id, &testWriter{1}}); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := wburst.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bursts, err := repository.Bursts()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if latency > 0 {
+			for i, id := range bursts {
+				bursts[i] = &testLatencyBurstId{id, latency}
+			}
+		}
+		var last TransactionId
+		if err := apply(&testRoot{}, &last, bursts); err != nil {
+			b.Fatal(err)
+		}
+		if last != id {
+			b.Fatal(last)
+		}
+	}
+}
+
+func BenchmarkApplyBursts(b *testing.B) {
+	benchmarkApplyBursts(b, func(root Root, last *TransactionId, bursts []BurstId) error {
+		return ApplyBursts(root, 0, last, bursts)
+	})
+}
+
+func BenchmarkApplyBurstsPipelined(b *testing.B) {
+	benchmarkApplyBursts(b, func(root Root, last *TransactionId, bursts []BurstId) error {
+		return ApplyBurstsPipelined(context.Background(), root, 0, last, bursts, 4, 256, nil)
+	})
+}
+
+func BenchmarkApplyBurstsLatency(b *testing.B) {
+	benchmarkApplyBurstsLatency(b, time.Millisecond, func(root Root, last *TransactionId, bursts []BurstId) error {
+		return ApplyBursts(root, 0, last, bursts)
+	})
+}
+
+// The buffers hold a whole Burst, so that the workers read ahead of the
+// Transactions being applied and their latencies overlap.
+func BenchmarkApplyBurstsPipelinedLatency(b *testing.B) {
+	benchmarkApplyBurstsLatency(b, time.Millisecond, func(root Root, last *TransactionId, bursts []BurstId) error {
+		return ApplyBurstsPipelined(context.Background(), root, 0, last, bursts, 4, 2000, nil)
+	})
+}
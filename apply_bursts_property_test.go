@@ -0,0 +1,115 @@
+package gobdb
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// A Root that records the sequence of Transactions applied.
+type testSeqRoot struct {
+	ids []TransactionId
+}
+
+type testSeqWriter struct {
+	Id TransactionId
+}
+
+func (op *testSeqWriter) Write(root Root) (interface{}, error) {
+	r := root.(*testSeqRoot)
+	r.ids = append(r.ids, op.Id)
+	return nil, nil
+}
+
+func init() {
+	RegisterWriter(&testSeqWriter{})
+}
+
+// It writes every Burst to a MemBurstRepository and checks that ApplyBursts()
+// and ApplyBurstsPipelined() apply the Transactions after the last one in
+// order, up to the first one that is in no Burst.
+func testApplyBurstsProperty(t *testing.T, lastId TransactionId, bursts [][]TransactionId) {
+
+	repository := NewMemBurstRepository()
+	present := make(map[TransactionId]bool)
+	for _, ids := range bursts {
+		wburst, err := repository.WriteBurst()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range ids {
+			if err := wburst.Write(Transaction{id, &testSeqWriter{id}}); err != nil {
+				t.Fatal(err)
+			}
+			present[id] = true
+		}
+		if err := wburst.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []TransactionId{}
+	for id := lastId + 1; present[id]; id++ {
+		expected = append(expected, id)
+	}
+	expectedLast := lastId + TransactionId(len(expected))
+
+	for _, pipelined := range []bool{false, true} {
+		burstIds, err := repository.Bursts()
+		if err != nil {
+			t.Fatal(err)
+		}
+		root := &testSeqRoot{[]TransactionId{}}
+		var last TransactionId
+		if pipelined {
+			err = ApplyBurstsPipelined(context.Background(), root, lastId, &last, burstIds, 2, 1, nil)
+		} else {
+			err = ApplyBursts(root, lastId, &last, burstIds)
+		}
+		if err != nil {
+			t.Error(pipelined, lastId, bursts, err)
+		}
+		if last != expectedLast || !reflect.DeepEqual(root.ids, expected) {
+			t.Error(pipelined, lastId, bursts, last, root.ids)
+		}
+	}
+}
+
+// It decodes the input of the fuzzer: the first byte is the last TransactionId
+// and every three bytes are a Burst, with its first TransactionId, its length
+// and a mask of the TransactionIds skipped.
+func testDecodeBursts(data []byte) (TransactionId, [][]TransactionId) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+	lastId := TransactionId(data[0] % 8)
+	bursts := [][]TransactionId{}
+	for data = data[1:]; len(data) >= 3 && len(bursts) < 16; data = data[3:] {
+		first, length, mask := TransactionId(data[0]%32+1), int(data[1]%8), data[2]
+		ids := []TransactionId{}
+		for i := 0; i < length; i++ {
+			if mask&(1<<uint(i)) == 0 {
+				ids = append(ids, first+TransactionId(i))
+			}
+		}
+		bursts = append(bursts, ids)
+	}
+	return lastId, bursts
+}
+
+func TestApplyBurstsRandom(t *testing.T) {
+
+	seed := time.Now().UnixNano()
+	random := rand.New(rand.NewSource(seed))
+	for n := 0; n < 200; n++ {
+		data := make([]byte, 1+3*random.Intn(12))
+		random.Read(data)
+		lastId, bursts := testDecodeBursts(data)
+		testApplyBurstsProperty(t, lastId, bursts)
+		if t.Failed() {
+			t.Fatal("seed", seed, "data", data)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package gobdb
 
 import (
+	"context"
 	"testing"
 )
 
@@ -69,3 +70,125 @@ func TestApplyBursts(t *testing.T) {
 		t.Error(root.counter)
 	}
 }
+
+// It writes a Burst with a testWriter{10 + id} per TransactionId.
+func testWriteBurst(t *testing.T, repository WriteBurstRepository, ids ...TransactionId) {
+	wburst, err := repository.WriteBurst()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range ids {
+		if err := wburst.Write(Transaction{id, &testWriter{10 + int(id)}}); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := wburst.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+// A Burst opened while another one is still being read must not drop the
+// other pending Bursts.
+func TestApplyBurstsOpenWhileReading(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 4)
+	testWriteBurst(t, repository, 2, 3)
+	testWriteBurst(t, repository, 2)
+
+	root := &testRoot{0}
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var id TransactionId
+	if err := ApplyBursts(root, 0, &id, bursts); err != nil {
+		t.Error(err)
+	}
+	if id != 4 {
+		t.Error(id)
+	}
+	if root.counter != 50 {
+		t.Error(root.counter)
+	}
+}
+
+func TestApplyBurstsContextProgress(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 2)
+	testWriteBurst(t, repository, 3, 4)
+
+	root := &testRoot{0}
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	progresses := []Progress{}
+	progress := func(p Progress) {
+		progresses = append(progresses, p)
+	}
+	var id TransactionId
+	if err := ApplyBurstsContext(context.Background(), root, 0, &id, bursts, progress); err != nil {
+		t.Error(err)
+	}
+	if id != 4 {
+		t.Error(id)
+	}
+	if root.counter != 50 {
+		t.Error(root.counter)
+	}
+	if len(progresses) != 6 {
+		t.Fatal(progresses)
+	}
+	if p := progresses[0]; p.Id != 0 || p.Target != 4 || p.Opened != 1 {
+		t.Error(p)
+	}
+	if p := progresses[5]; p.Id != 4 || p.Target != 4 || p.Opened != 2 || p.Bytes <= 0 {
+		t.Error(p)
+	}
+	for i := 1; i < len(progresses); i++ {
+		if progresses[i].Bytes < progresses[i-1].Bytes {
+			t.Error(i, progresses[i])
+		}
+	}
+}
+
+func TestApplyBurstsContextCancel(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 2, 3, 4)
+
+	root := &testRoot{0}
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	progress := func(p Progress) {
+		if p.Id == 2 {
+			cancel()
+		}
+	}
+	var id TransactionId
+	if err := ApplyBurstsContext(ctx, root, 0, &id, bursts, progress); err != context.Canceled {
+		t.Error(err)
+	}
+	if id != 2 {
+		t.Error(id)
+	}
+	if root.counter != 23 {
+		t.Error(root.counter)
+	}
+
+	if err := ApplyBursts(root, id, &id, bursts); err != nil {
+		t.Error(err)
+	}
+	if id != 4 {
+		t.Error(id)
+	}
+	if root.counter != 50 {
+		t.Error(root.counter)
+	}
+}
@@ -1,12 +1,20 @@
 package gobdb
 
 import (
+	"context"
 	"errors"
 	"io"
 )
 
 // It applies the Writers of a Snapshot to a Root.
 func ApplySnapshot(root Root, snapshotId SnapshotId) error {
+	return ApplySnapshotContext(context.Background(), root, snapshotId, nil)
+}
+
+// Like ApplySnapshot(), but it stops with the error of the Context as soon as
+// it is cancelled and it invokes the optional function after every applied
+// Writer. The Id of the Progress is only set once the Snapshot is complete.
+func ApplySnapshotContext(ctx context.Context, root Root, snapshotId SnapshotId, progress func(Progress)) error {
 
 	reader, err := snapshotId.Read()
 	if err != nil {
@@ -14,11 +22,28 @@ func ApplySnapshot(root Root, snapshotId SnapshotId) error {
 	}
 	defer reader.Close()
 
+	status := Progress{Target: snapshotId.Id(), Opened: 1}
+	report := func() {
+		if progress != nil {
+			status.Bytes = bytesRead(reader)
+			progress(status)
+		}
+	}
+	report()
+
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		writer, err := reader.Read()
 		if err != nil {
 			if err == io.EOF {
-				return reader.Close()
+				if err := reader.Close(); err != nil {
+					return err
+				}
+				status.Id = snapshotId.Id()
+				report()
+				return nil
 			}
 			return err
 		}
@@ -28,5 +53,6 @@ func ApplySnapshot(root Root, snapshotId SnapshotId) error {
 		if _, err := writer.Write(root); err != nil {
 			return err
 		}
+		report()
 	}
 }
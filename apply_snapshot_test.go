@@ -1,6 +1,7 @@
 package gobdb
 
 import (
+	"context"
 	"testing"
 )
 
@@ -44,3 +45,59 @@ func TestApplySnapshot(t *testing.T) {
 		t.Error(root.counter)
 	}
 }
+
+func TestApplySnapshotContext(t *testing.T) {
+
+	repository := NewMemSnapshotRepository()
+	wsnapshot, err := repository.WriteSnapshot(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wsnapshot.Write(&testWriter{11}); err != nil {
+		t.Error(err)
+	}
+	if err := wsnapshot.Write(&testWriter{12}); err != nil {
+		t.Error(err)
+	}
+	if err := wsnapshot.Close(); err != nil {
+		t.Error(err)
+	}
+	snapshots, err := repository.Snapshots()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(snapshots) != 1 {
+		t.Fatal(len(snapshots))
+	}
+
+	root := &testRoot{}
+	progresses := []Progress{}
+	progress := func(p Progress) {
+		progresses = append(progresses, p)
+	}
+	if err := ApplySnapshotContext(context.Background(), root, snapshots[0], progress); err != nil {
+		t.Error(err)
+	}
+	if root.counter != 23 {
+		t.Error(root.counter)
+	}
+	if len(progresses) != 4 {
+		t.Fatal(progresses)
+	}
+	if p := progresses[2]; p.Id != 0 || p.Target != 2 || p.Opened != 1 || p.Bytes <= 0 {
+		t.Error(p)
+	}
+	if p := progresses[3]; p.Id != 2 || p.Target != 2 || p.Opened != 1 {
+		t.Error(p)
+	}
+
+	root = &testRoot{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ApplySnapshotContext(ctx, root, snapshots[0], nil); err != context.Canceled {
+		t.Error(err)
+	}
+	if root.counter != 0 {
+		t.Error(root.counter)
+	}
+}
@@ -0,0 +1,189 @@
+package gobdb
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"sync"
+)
+
+// BurstDispatcher that writes to another in a background goroutine, through a
+// bounded queue. Write() blocks while the queue is full. The first error of the
+// other BurstDispatcher is returned by the next calls and by Close(), and the
+// following Transactions are discarded.
+// The Writers are gob encoded before they are enqueued and decoded again by
+// the background goroutine, so they may be modified once Write() returns.
+// No thread-safe, but AsyncCommits are, and Rotate() and Sync() may run
+// concurrently with Write() and WriteAsync().
+type AsyncBurstDispatcher struct {
+	queue      chan asyncBurstDispatcherOp
+	done       chan struct{}
+	dispatcher BurstDispatcher
+	mutex      sync.Mutex
+	err        error
+	closed     bool
+}
+
+type asyncBurstDispatcherOp struct {
+	id     TransactionId
+	writer []byte
+	rotate bool
+	sync   bool
+	commit *AsyncCommit
+}
+
+// The notification of the write of a Transaction by an AsyncBurstDispatcher.
+type AsyncCommit struct {
+	done chan struct{}
+	err  error
+}
+
+// New instance. The size of the queue is the number of Transactions that may
+// be pending.
+func NewAsyncBurstDispatcher(size int, dispatcher BurstDispatcher) *AsyncBurstDispatcher {
+	queue := make(chan asyncBurstDispatcherOp, size)
+	bd := &AsyncBurstDispatcher{queue, make(chan struct{}), dispatcher, sync.Mutex{}, nil, false}
+	go bd.run()
+	return bd
+}
+
+func (bd *AsyncBurstDispatcher) run() {
+	defer close(bd.done)
+	for op := range bd.queue {
+		err := bd.error()
+		if err == nil {
+			if op.writer != nil {
+				var writer Writer
+				if err = gob.NewDecoder(bytes.NewReader(op.writer)).Decode(&writer); err == nil {
+					err = bd.dispatcher.Write(Transaction{op.id, writer})
+				}
+			} else if op.rotate {
+				err = bd.dispatcher.Rotate()
+			} else if syncer, ok := bd.dispatcher.(Syncer); ok && op.sync {
+				err = syncer.Sync()
+			}
+			if err != nil {
+				bd.setError(err)
+			}
+		}
+		if op.commit != nil {
+			op.commit.err = err
+			close(op.commit.done)
+		}
+	}
+	if err := bd.dispatcher.Close(); err != nil {
+		bd.setError(err)
+	}
+}
+
+func (bd *AsyncBurstDispatcher) error() error {
+	bd.mutex.Lock()
+	defer bd.mutex.Unlock()
+	return bd.err
+}
+
+func (bd *AsyncBurstDispatcher) setError(err error) {
+	bd.mutex.Lock()
+	defer bd.mutex.Unlock()
+	if bd.err == nil {
+		bd.err = err
+	}
+}
+
+// It returns the previous error or enqueues the operation.
+func (bd *AsyncBurstDispatcher) enqueue(op asyncBurstDispatcherOp) error {
+	if bd.closed {
+		return errors.New("gobdb: write() on closed BurstDispatcher")
+	}
+	if err := bd.error(); err != nil {
+		return err
+	}
+	bd.queue <- op
+	return nil
+}
+
+// Implements BurstDispatcher.Write(). It returns once the Transaction is
+// encoded and enqueued. If the Writer is not gob encodable, it returns the
+// error and the AsyncBurstDispatcher can still be used.
+func (bd *AsyncBurstDispatcher) Write(transaction Transaction) error {
+	return bd.enqueueTransaction(transaction, nil)
+}
+
+// Like Write(), but it returns the notification of the write of the
+// Transaction by the other BurstDispatcher.
+func (bd *AsyncBurstDispatcher) WriteAsync(transaction Transaction) *AsyncCommit {
+	commit := &AsyncCommit{make(chan struct{}), nil}
+	if err := bd.enqueueTransaction(transaction, commit); err != nil {
+		commit.err = err
+		close(commit.done)
+	}
+	return commit
+}
+
+// It encodes the Writer and enqueues it.
+func (bd *AsyncBurstDispatcher) enqueueTransaction(transaction Transaction, commit *AsyncCommit) error {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(&transaction.Writer); err != nil {
+		return err
+	}
+	return bd.enqueue(asyncBurstDispatcherOp{transaction.Id, buffer.Bytes(), false, false, commit})
+}
+
+// Implements Syncer.Sync(). It waits until the Transactions enqueued have been
+// written by the other BurstDispatcher, and synced if it implements Syncer.
+func (bd *AsyncBurstDispatcher) Sync() error {
+	commit := &AsyncCommit{make(chan struct{}), nil}
+	if err := bd.enqueue(asyncBurstDispatcherOp{0, nil, false, true, commit}); err != nil {
+		return err
+	}
+	return commit.Wait()
+}
+
+// Implements BurstDispatcher.Rotate(). It returns once the rotation is
+// enqueued.
+func (bd *AsyncBurstDispatcher) Rotate() error {
+	return bd.enqueue(asyncBurstDispatcherOp{0, nil, true, false, nil})
+}
+
+// Implements LockBurstDispatcher.Lock(). It does nothing if the other
+// BurstDispatcher does not implement it.
+func (bd *AsyncBurstDispatcher) Lock() error {
+	if locker, ok := bd.dispatcher.(LockBurstDispatcher); ok {
+		return locker.Lock()
+	}
+	return nil
+}
+
+// Implements HighWaterMarker.HighWaterMark(). It does not include the
+// Transactions that are still enqueued. It is zero if the other
+// BurstDispatcher does not implement it.
+func (bd *AsyncBurstDispatcher) HighWaterMark() (TransactionId, error) {
+	if marker, ok := bd.dispatcher.(HighWaterMarker); ok {
+		return marker.HighWaterMark()
+	}
+	return 0, nil
+}
+
+// Implements BurstDispatcher.Close(). It waits until the Transactions enqueued
+// have been written, closes the other BurstDispatcher and returns the first
+// error.
+func (bd *AsyncBurstDispatcher) Close() error {
+	if bd.closed {
+		return errors.New("gobdb: close() on closed BurstDispatcher")
+	}
+	bd.closed = true
+	close(bd.queue)
+	<-bd.done
+	return bd.error()
+}
+
+// It is closed when the Transaction has been written or discarded.
+func (c *AsyncCommit) Done() <-chan struct{} {
+	return c.done
+}
+
+// It waits until the Transaction has been written and returns its error.
+func (c *AsyncCommit) Wait() error {
+	<-c.done
+	return c.err
+}
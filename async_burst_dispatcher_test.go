@@ -0,0 +1,215 @@
+package gobdb
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// A BurstDispatcher that waits for the gate on every Write() and fails at the
+// given TransactionId.
+type testGateBurstDispatcher struct {
+	gate    chan struct{}
+	fail    TransactionId
+	mutex   sync.Mutex
+	started int
+	ids     []TransactionId
+	writers []Writer
+	closed  bool
+}
+
+func (bd *testGateBurstDispatcher) Write(transaction Transaction) error {
+	bd.mutex.Lock()
+	bd.started++
+	bd.mutex.Unlock()
+	<-bd.gate
+	if transaction.Id == bd.fail {
+		return errors.New("test")
+	}
+	bd.mutex.Lock()
+	defer bd.mutex.Unlock()
+	bd.ids = append(bd.ids, transaction.Id)
+	bd.writers = append(bd.writers, transaction.Writer)
+	return nil
+}
+
+func (bd *testGateBurstDispatcher) Rotate() error {
+	return nil
+}
+
+func (bd *testGateBurstDispatcher) Close() error {
+	bd.closed = true
+	return nil
+}
+
+func (bd *testGateBurstDispatcher) startedWrites() int {
+	bd.mutex.Lock()
+	defer bd.mutex.Unlock()
+	return bd.started
+}
+
+func (bd *testGateBurstDispatcher) written() int {
+	bd.mutex.Lock()
+	defer bd.mutex.Unlock()
+	return len(bd.ids)
+}
+
+func TestAsyncBurstDispatcherInterface(t *testing.T) {
+
+	dispatcher := NewAsyncBurstDispatcher(1, NewDefaultBurstDispatcher(NewMemBurstRepository()))
+	defer dispatcher.Close()
+	var i interface{} = dispatcher
+	if _, ok := i.(BurstDispatcher); !ok {
+		t.Error(i)
+	}
+	if _, ok := i.(HighWaterMarker); !ok {
+		t.Error(i)
+	}
+}
+
+func TestAsyncBurstDispatcher(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	dispatcher := NewAsyncBurstDispatcher(2, NewDefaultBurstDispatcher(repository))
+	database := NewDefaultDatabase(&testRoot{}, 0, dispatcher)
+	for i := 1; i <= 10; i++ {
+		if _, err1, err2 := database.Write(&testWriter{i}); err1 != nil || err2 != nil {
+			t.Error(err1, err2)
+		}
+	}
+	if err := dispatcher.Rotate(); err != nil {
+		t.Error(err)
+	}
+	if err := dispatcher.WriteAsync(Transaction{11, &testWriter{11}}).Wait(); err != nil {
+		t.Error(err)
+	}
+	if err := dispatcher.Sync(); err != nil {
+		t.Error(err)
+	}
+	if bursts, err := repository.Bursts(); len(bursts) != 2 || err != nil {
+		t.Error(bursts, err)
+	}
+	if mark, err := dispatcher.HighWaterMark(); err != nil || mark != 11 {
+		t.Error(mark, err)
+	}
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := dispatcher.Close(); err == nil {
+		t.Error(err)
+	}
+	if err := dispatcher.Write(Transaction{12, &testWriter{12}}); err == nil {
+		t.Error(err)
+	}
+
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(bursts) != 2 {
+		t.Error(bursts)
+	}
+	root := &testRoot{}
+	var id TransactionId
+	if err := ApplyBursts(root, 0, &id, bursts); err != nil {
+		t.Error(err)
+	}
+	if id != 11 || root.counter != 66 {
+		t.Error(id, root.counter)
+	}
+}
+
+func TestAsyncBurstDispatcherBackPressure(t *testing.T) {
+
+	inner := &testGateBurstDispatcher{gate: make(chan struct{})}
+	dispatcher := NewAsyncBurstDispatcher(1, inner)
+
+	// the first one is being written and the second one is enqueued
+	commit := dispatcher.WriteAsync(Transaction{1, &testWriter{1}})
+	if err := dispatcher.Write(Transaction{2, &testWriter{2}}); err != nil {
+		t.Error(err)
+	}
+	select {
+	case <-commit.Done():
+		t.Error("not blocked")
+	default:
+	}
+
+	// the third one is enqueued once the second one is being written, which
+	// needs the first one to pass the gate
+	blocked := make(chan int)
+	go func() {
+		if err := dispatcher.Write(Transaction{3, &testWriter{3}}); err != nil {
+			t.Error(err)
+		}
+		blocked <- inner.startedWrites()
+	}()
+
+	inner.gate <- struct{}{}
+	if err := commit.Wait(); err != nil {
+		t.Error(err)
+	}
+	if started := <-blocked; started < 2 {
+		t.Error(started)
+	}
+	close(inner.gate)
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+	if inner.written() != 3 || !inner.closed {
+		t.Error(inner.ids, inner.closed)
+	}
+}
+
+func TestAsyncBurstDispatcherError(t *testing.T) {
+
+	inner := &testGateBurstDispatcher{gate: make(chan struct{}), fail: 2}
+	close(inner.gate)
+	dispatcher := NewAsyncBurstDispatcher(10, inner)
+
+	if err := dispatcher.Write(Transaction{1, &testWriter{1}}); err != nil {
+		t.Error(err)
+	}
+	commit := dispatcher.WriteAsync(Transaction{2, &testWriter{2}})
+	if err := commit.Wait(); err == nil {
+		t.Error(err)
+	}
+	if err := dispatcher.Write(Transaction{3, &testWriter{3}}); err == nil {
+		t.Error(err)
+	}
+	if err := dispatcher.Sync(); err == nil {
+		t.Error(err)
+	}
+	if err := dispatcher.Close(); err == nil {
+		t.Error(err)
+	}
+	if inner.written() != 1 || !inner.closed {
+		t.Error(inner.ids, inner.closed)
+	}
+}
+
+func TestAsyncBurstDispatcherModifiedWriter(t *testing.T) {
+
+	inner := &testGateBurstDispatcher{gate: make(chan struct{})}
+	dispatcher := NewAsyncBurstDispatcher(1, inner)
+
+	writer := &testWriter{1}
+	if err := dispatcher.Write(Transaction{1, writer}); err != nil {
+		t.Error(err)
+	}
+	writer.Increment = 2
+	if err := dispatcher.Write(Transaction{2, &testUnregisteredWriter{2}}); err == nil {
+		t.Error(err)
+	}
+
+	close(inner.gate)
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+	if len(inner.writers) != 1 {
+		t.Fatal(inner.writers)
+	}
+	if w, ok := inner.writers[0].(*testWriter); !ok || w.Increment != 1 {
+		t.Errorf("%#v", inner.writers[0])
+	}
+}
@@ -0,0 +1,325 @@
+package gobdb
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+const backupManifestName = "manifest.gobdb"
+
+// The contents of a backup, written by Backup() into its directory.
+type BackupManifest struct {
+	// The last TransactionId in the backup.
+	LastId TransactionId
+	// The Snapshot and its delta Snapshots, in the order they must be applied.
+	Snapshots []BackupFile
+	// The Bursts to apply after the Snapshots, in order.
+	Bursts []BackupFile
+}
+
+// A file of a backup.
+type BackupFile struct {
+	Name string
+	Size int64
+	// The SHA-256 of its contents.
+	Sum []byte
+}
+
+// The error returned by Restore() when a backup is incomplete or corrupted.
+type ErrInvalidBackup struct {
+	Name   string
+	Reason string
+}
+
+func (e *ErrInvalidBackup) Error() string {
+	return fmt.Sprintf("gobdb: invalid backup file %s: %s", e.Name, e.Reason)
+}
+
+// It backs up the repositories of a live database into a new directory of the
+// FileSystem of the DirBurstRepository. It rotates the BurstDispatcher, and
+// syncs it if it implements Syncer, so that the Transactions written so far
+// are in closed Bursts. The caller must not run it concurrently with the
+// writes of the database, unless the BurstDispatcher is an
+// AsyncBurstDispatcher, whose rotation is enqueued among them. Then it chooses the newest Snapshot and the
+// Bursts after it with PlanRecovery() and it hard-links them into the
+// directory, or copies them if they can not be linked. The BurstDispatcher
+// and the SnapshotRepository are optional. The Bursts
+// must not be merged or removed meanwhile. The manifest is written last, so a
+// backup without it is incomplete.
+func Backup(dispatcher BurstDispatcher, bursts *DirBurstRepository, snapshots *DirSnapshotRepository, dir string) (*BackupManifest, error) {
+
+	if dispatcher != nil {
+		if err := dispatcher.Rotate(); err != nil {
+			return nil, err
+		}
+		if syncer, ok := dispatcher.(Syncer); ok {
+			if err := syncer.Sync(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var repository SnapshotRepository
+	if snapshots != nil {
+		repository = snapshots
+	}
+	plan, err := planRepositories(repository, bursts)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := bursts.fs
+	if err := fs.MkdirAll(dir); err != nil {
+		return nil, err
+	}
+	if _, err := fs.Stat(filepath.Join(dir, backupManifestName)); err == nil {
+		return nil, fmt.Errorf("gobdb: %s already contains a backup", dir)
+	}
+
+	manifest := &BackupManifest{plan.Target, []BackupFile{}, []BackupFile{}}
+	if plan.Snapshot != nil {
+		for _, id := range append([]SnapshotId{plan.Snapshot}, plan.Deltas...) {
+			var base TransactionId
+			if delta, ok := id.(DeltaSnapshotId); ok {
+				base = delta.Base()
+			}
+			file, err := backupFile(snapshots.fs, snapshots.dir, fs, dir, dirSnapshotRepositoryFileName(base, id.Id()))
+			if err != nil {
+				return nil, err
+			}
+			manifest.Snapshots = append(manifest.Snapshots, file)
+		}
+	}
+	for _, id := range plan.Bursts {
+		name := fmt.Sprintf(dirBurstRepositoryFileNameFormat, id.First(), id.Last())
+		if did, ok := id.(*dirBurstId); ok {
+			name = filepath.Base(did.path())
+		}
+		file, err := backupFile(fs, bursts.dir, fs, dir, name)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Bursts = append(manifest.Bursts, file)
+	}
+
+	if err := writeBackupManifest(fs, dir, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// It links or copies a file between two directories and returns its
+// BackupFile.
+func backupFile(fromFS FileSystem, from string, toFS FileSystem, to string, name string) (BackupFile, error) {
+	if err := linkFile(fromFS, filepath.Join(from, name), toFS, filepath.Join(to, name)); err != nil {
+		return BackupFile{}, err
+	}
+	size, sum, err := sumFile(toFS, filepath.Join(to, name))
+	if err != nil {
+		return BackupFile{}, err
+	}
+	return BackupFile{name, size, sum}, nil
+}
+
+// It hard-links a file if both FileSystems are the same one, or copies it
+// otherwise or if it can not. It fails if the new file exists.
+func linkFile(fromFS FileSystem, from string, toFS FileSystem, to string) error {
+	if fromFS == toFS {
+		if err := toFS.Link(from, to); err == nil {
+			return nil
+		}
+	}
+	if _, err := toFS.Stat(to); err == nil {
+		return fmt.Errorf("gobdb: %s already exists", to)
+	}
+	src, err := fromFS.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := toFS.CreateTemp(filepath.Dir(to), "tmp-copy-")
+	if err != nil {
+		return err
+	}
+	_, err1 := io.Copy(dst, src)
+	if err1 == nil {
+		err1 = dst.Sync()
+	}
+	err2 := dst.Close()
+	for _, err := range []error{err1, err2} {
+		if err != nil {
+			toFS.Remove(dst.Name())
+			return err
+		}
+	}
+	return toFS.Rename(dst.Name(), to)
+}
+
+// It returns the size and the SHA-256 of a file.
+func sumFile(fs FileSystem, path string) (int64, []byte, error) {
+	file, err := fs.Open(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, nil, err
+	}
+	return size, hash.Sum(nil), nil
+}
+
+// It writes the manifest and syncs the directory, with the files linked.
+func writeBackupManifest(fs FileSystem, dir string, manifest *BackupManifest) error {
+	file, err := fs.CreateTemp(dir, "tmp-manifest-")
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	err1 := gob.NewEncoder(writer).Encode(manifest)
+	if err1 == nil {
+		err1 = writer.Flush()
+	}
+	if err1 == nil {
+		err1 = file.Sync()
+	}
+	err2 := file.Close()
+	for _, err := range []error{err1, err2} {
+		if err != nil {
+			fs.Remove(file.Name())
+			return err
+		}
+	}
+	if err := fs.Rename(file.Name(), filepath.Join(dir, backupManifestName)); err != nil {
+		return err
+	}
+	return fs.SyncDir(dir)
+}
+
+// It reads the manifest of a backup.
+func ReadBackupManifest(dir string) (*BackupManifest, error) {
+	return ReadBackupManifestFS(OSFileSystem{}, dir)
+}
+
+// Like ReadBackupManifest(), on the given FileSystem.
+func ReadBackupManifestFS(fs FileSystem, dir string) (*BackupManifest, error) {
+	file, err := fs.Open(filepath.Join(dir, backupManifestName))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	manifest := &BackupManifest{}
+	if err := gob.NewDecoder(bufio.NewReader(file)).Decode(manifest); err != nil {
+		return nil, &ErrInvalidBackup{backupManifestName, err.Error()}
+	}
+	return manifest, nil
+}
+
+// It validates a backup written by Backup() and restores it into a directory
+// for the Bursts and another one for the Snapshots, which may be the same one.
+// Every file must have the size and the SHA-256 of the manifest, and they
+// must recover the state until its last TransactionId, or it returns an
+// *ErrInvalidBackup. The directories are created if needed, and they must not
+// contain Bursts or Snapshots.
+func Restore(dir, burstDir, snapshotDir string) (*BackupManifest, error) {
+	return RestoreFS(OSFileSystem{}, dir, burstDir, snapshotDir)
+}
+
+// Like Restore(), on the given FileSystem.
+func RestoreFS(fs FileSystem, dir, burstDir, snapshotDir string) (*BackupManifest, error) {
+
+	manifest, err := ReadBackupManifestFS(fs, dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBackup(fs, dir, manifest); err != nil {
+		return nil, err
+	}
+
+	bursts := NewDirBurstRepositoryFS(burstDir, fs)
+	snapshots := NewDirSnapshotRepositoryFS(snapshotDir, fs)
+	for _, d := range []string{burstDir, snapshotDir} {
+		if err := fs.MkdirAll(d); err != nil {
+			return nil, err
+		}
+	}
+	if plan, err := planRepositories(snapshots, bursts); err != nil {
+		return nil, err
+	} else if plan.Target != 0 {
+		return nil, fmt.Errorf("gobdb: %s or %s is not empty", burstDir, snapshotDir)
+	}
+
+	for _, file := range manifest.Snapshots {
+		if err := linkFile(fs, filepath.Join(dir, file.Name), fs, filepath.Join(snapshotDir, file.Name)); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range manifest.Bursts {
+		if err := linkFile(fs, filepath.Join(dir, file.Name), fs, filepath.Join(burstDir, file.Name)); err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range []string{burstDir, snapshotDir} {
+		if err := fs.SyncDir(d); err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+// It checks the files of a backup and that they cover its Transactions.
+func validateBackup(fs FileSystem, dir string, manifest *BackupManifest) error {
+
+	for _, file := range append(append([]BackupFile{}, manifest.Snapshots...), manifest.Bursts...) {
+		size, sum, err := sumFile(fs, filepath.Join(dir, file.Name))
+		if err != nil {
+			return &ErrInvalidBackup{file.Name, err.Error()}
+		}
+		if size != file.Size || !bytes.Equal(sum, file.Sum) {
+			return &ErrInvalidBackup{file.Name, "its contents do not match the manifest"}
+		}
+	}
+
+	bursts := NewDirBurstRepositoryFS(dir, fs)
+	snapshots := NewDirSnapshotRepositoryFS(dir, fs)
+	snapshotIds := make([]SnapshotId, 0, len(manifest.Snapshots))
+	for _, file := range manifest.Snapshots {
+		var base, id int
+		if n, err := fmt.Sscanf(file.Name+"\n", dirSnapshotRepositoryDeltaFileNameScanFormat, &base, &id); n == 2 && err == nil {
+			snapshotIds = append(snapshotIds, &dirSnapshotId{TransactionId(id), TransactionId(base), snapshots})
+		} else if n, err := fmt.Sscanf(file.Name+"\n", dirSnapshotRepositoryFileNameScanFormat, &id); n == 1 && err == nil {
+			snapshotIds = append(snapshotIds, &dirSnapshotId{TransactionId(id), 0, snapshots})
+		} else {
+			return &ErrInvalidBackup{file.Name, "it is not a Snapshot"}
+		}
+	}
+	burstIds := make([]BurstId, 0, len(manifest.Bursts))
+	for _, file := range manifest.Bursts {
+		var first, last int
+		if n, err := fmt.Sscanf(file.Name+"\n", dirBurstRepositoryFileNameScanFormat, &first, &last); n == 2 && err == nil {
+			burstIds = append(burstIds, &dirBurstId{TransactionId(first), TransactionId(last), bursts, false})
+		} else if n, err := fmt.Sscanf(file.Name+"\n", dirBurstRepositoryOpenFileNameScanFormat, &first); n == 1 && err == nil {
+			id := &dirBurstId{TransactionId(first), 0, bursts, true}
+			if id.last, err = id.scanLast(); err != nil {
+				return err
+			}
+			burstIds = append(burstIds, id)
+		} else {
+			return &ErrInvalidBackup{file.Name, "it is not a Burst"}
+		}
+	}
+
+	plan, err := PlanRecovery(manifest.LastId, snapshotIds, burstIds)
+	if err != nil {
+		return &ErrInvalidBackup{backupManifestName, err.Error()}
+	}
+	if plan.Target != manifest.LastId || (plan.Snapshot == nil && len(manifest.Snapshots) > 0) {
+		return &ErrInvalidBackup{backupManifestName, "its files do not recover its last TransactionId"}
+	}
+	return nil
+}
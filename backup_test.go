@@ -0,0 +1,232 @@
+package gobdb
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// It writes a database with a Snapshot at 5 and Bursts until 8, backs it up
+// and keeps writing.
+func testBackup(t *testing.T, dir string) *BackupManifest {
+
+	bursts := NewDirBurstRepository(filepath.Join(dir, "db"))
+	snapshots := NewDirSnapshotRepository(filepath.Join(dir, "db"))
+	if err := os.Mkdir(filepath.Join(dir, "db"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	dispatcher := NewAsyncBurstDispatcher(4, NewNumTransactionsBurstDispatcher(2, NewDefaultBurstDispatcher(bursts)))
+	database := NewDefaultDatabase(&testRoot{0}, 0, dispatcher)
+
+	for i := 1; i <= 8; i++ {
+		if _, err1, err2 := database.Write(&testWriter{i}); err1 != nil || err2 != nil {
+			t.Error(err1, err2)
+		}
+		if i == 5 {
+			if err := database.TakeSnapshot(testSnapshooter, snapshots); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+
+	manifest, err := Backup(dispatcher, bursts, snapshots, filepath.Join(dir, "backup"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 9; i <= 10; i++ {
+		if _, err1, err2 := database.Write(&testWriter{i}); err1 != nil || err2 != nil {
+			t.Error(err1, err2)
+		}
+	}
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+	return manifest
+}
+
+func TestBackup(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	manifest := testBackup(t, dir)
+	if manifest.LastId != 8 {
+		t.Error(manifest.LastId)
+	}
+	if len(manifest.Snapshots) != 1 || manifest.Snapshots[0].Name != "snapshot-5.gobdb" {
+		t.Error(manifest.Snapshots)
+	}
+	if len(manifest.Bursts) != 2 || manifest.Bursts[0].Name != "burst-5-6.gobdb" {
+		t.Error(manifest.Bursts)
+	}
+	if _, err := Backup(nil, NewDirBurstRepository(filepath.Join(dir, "db")), nil, filepath.Join(dir, "backup")); err == nil {
+		t.Error(err)
+	}
+
+	restored := filepath.Join(dir, "restored")
+	if _, err := Restore(filepath.Join(dir, "backup"), restored, restored); err != nil {
+		t.Fatal(err)
+	}
+	root := &testRoot{0}
+	var id TransactionId
+	if _, err := Recover(context.Background(), root, NewDirSnapshotRepository(restored), NewDirBurstRepository(restored), &id, nil); err != nil {
+		t.Error(err)
+	}
+	if id != 8 || root.counter != 8*9/2 {
+		t.Error(id, root.counter)
+	}
+
+	if _, err := Restore(filepath.Join(dir, "backup"), restored, restored); err == nil {
+		t.Error(err)
+	}
+}
+
+func TestBackupDefaultBurstDispatcher(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bursts := NewDirBurstRepository(dir)
+	dispatcher := NewDefaultBurstDispatcher(bursts)
+	database := NewDefaultDatabase(&testRoot{0}, 0, dispatcher)
+	for i := 1; i <= 3; i++ {
+		if _, err1, err2 := database.Write(&testWriter{i}); err1 != nil || err2 != nil {
+			t.Error(err1, err2)
+		}
+	}
+	manifest, err := Backup(dispatcher, bursts, nil, filepath.Join(dir, "backup"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.LastId != 3 || len(manifest.Bursts) != 1 {
+		t.Error(manifest)
+	}
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBackupConcurrentWrites(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bursts := NewDirBurstRepository(dir)
+	dispatcher := NewAsyncBurstDispatcher(4, NewNumTransactionsBurstDispatcher(3, NewDefaultBurstDispatcher(bursts)))
+	database := NewDefaultDatabase(&testRoot{0}, 0, dispatcher)
+
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if _, err1, err2 := database.Write(&testWriter{1}); err1 != nil || err2 != nil {
+				t.Error(err1, err2)
+				return
+			}
+		}
+	}()
+
+	manifests := []*BackupManifest{}
+	for i := 0; i < 5; i++ {
+		manifest, err := Backup(dispatcher, bursts, nil, filepath.Join(dir, "backup", strconv.Itoa(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifests = append(manifests, manifest)
+	}
+	close(stop)
+	<-done
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+
+	for i, manifest := range manifests {
+		restored := filepath.Join(dir, "restored", strconv.Itoa(i))
+		if _, err := Restore(filepath.Join(dir, "backup", strconv.Itoa(i)), restored, restored); err != nil {
+			t.Fatal(err)
+		}
+		root := &testRoot{0}
+		var id TransactionId
+		if _, err := Recover(context.Background(), root, NewDirSnapshotRepository(restored), NewDirBurstRepository(restored), &id, nil); err != nil {
+			t.Error(err)
+		}
+		if id != manifest.LastId || root.counter != int(id) {
+			t.Error(id, manifest.LastId, root.counter)
+		}
+	}
+}
+
+func TestRestoreInvalid(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	backup := filepath.Join(dir, "backup")
+	manifest := testBackup(t, dir)
+
+	// a modified file
+	name := filepath.Join(backup, manifest.Bursts[1].Name)
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(name, append(data, 0), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Restore(backup, filepath.Join(dir, "r1"), filepath.Join(dir, "r1")); err == nil {
+		t.Error(err)
+	} else if _, ok := err.(*ErrInvalidBackup); !ok {
+		t.Error(err)
+	}
+
+	// a missing file
+	if err := os.Remove(name); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Restore(backup, filepath.Join(dir, "r2"), filepath.Join(dir, "r2")); err == nil {
+		t.Error(err)
+	} else if _, ok := err.(*ErrInvalidBackup); !ok {
+		t.Error(err)
+	}
+
+	// a manifest whose files do not cover its Transactions
+	manifest.Bursts = manifest.Bursts[:1]
+	if err := os.Remove(filepath.Join(backup, backupManifestName)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeBackupManifest(OSFileSystem{}, backup, manifest); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Restore(backup, filepath.Join(dir, "r3"), filepath.Join(dir, "r3")); err == nil {
+		t.Error(err)
+	} else if _, ok := err.(*ErrInvalidBackup); !ok {
+		t.Error(err)
+	}
+
+	if _, err := Restore(filepath.Join(dir, "none"), filepath.Join(dir, "r4"), filepath.Join(dir, "r4")); err == nil {
+		t.Error(err)
+	}
+}
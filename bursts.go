@@ -13,6 +13,14 @@ type BurstId interface {
 	Read() (BurstReader, error)
 }
 
+// An optional interface of BurstIds that can skip the first Transactions.
+type SeekBurstId interface {
+	BurstId
+	// Get a BurstReader of a Burst whose next Transaction is the given one or
+	// one before it.
+	ReadFrom(TransactionId) (BurstReader, error)
+}
+
 // It reads the Transactions of a Burst.
 type BurstReader interface {
 	// The id of the Burst.
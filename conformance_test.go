@@ -0,0 +1,50 @@
+package gobdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+	"github.com/daniel-fanjul-alcuten/gobdb/gobdbfault"
+	"github.com/daniel-fanjul-alcuten/gobdb/gobdbtest"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestMemBurstRepositoryConformance(t *testing.T) {
+	gobdbtest.TestBurstRepository(t, func(t *testing.T) gobdbtest.BurstRepository {
+		return gobdb.NewMemBurstRepository()
+	})
+}
+
+func TestDirBurstRepositoryConformance(t *testing.T) {
+	gobdbtest.TestBurstRepository(t, func(t *testing.T) gobdbtest.BurstRepository {
+		return gobdb.NewDirBurstRepository(tempDir(t))
+	})
+	gobdbtest.TestBurstRepository(t, func(t *testing.T) gobdbtest.BurstRepository {
+		return gobdb.NewDirBurstRepositoryFS("/db", gobdbfault.NewFileSystem(nil))
+	})
+}
+
+func TestMemSnapshotRepositoryConformance(t *testing.T) {
+	gobdbtest.TestSnapshotRepository(t, func(t *testing.T) gobdbtest.SnapshotRepository {
+		return gobdb.NewMemSnapshotRepository()
+	})
+}
+
+func TestDirSnapshotRepositoryConformance(t *testing.T) {
+	gobdbtest.TestSnapshotRepository(t, func(t *testing.T) gobdbtest.SnapshotRepository {
+		return gobdb.NewDirSnapshotRepository(tempDir(t))
+	})
+	gobdbtest.TestSnapshotRepository(t, func(t *testing.T) gobdbtest.SnapshotRepository {
+		return gobdb.NewDirSnapshotRepositoryFS("/db", gobdbfault.NewFileSystem(nil))
+	})
+}
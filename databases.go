@@ -45,3 +45,24 @@ type SnapshotDatabase interface {
 	// WriteSnapshotRepository.
 	TakeSnapshot(Snapshooter, WriteSnapshotRepository) error
 }
+
+// An optional interface of Roots that track the state modified by the
+// Writers, so that delta Snapshots can be taken.
+type DirtyTracker interface {
+	// It invokes the given function as many times as needed with the sequence
+	// of Writers that are enough to recover the state modified since the last
+	// ClearDirty(). It the given function returns an errors, it must be
+	// returned immediately.
+	SnapshotDirty(func(...Writer) error) error
+	// It forgets the modified state.
+	ClearDirty()
+}
+
+// A database that can take delta snapshots of a Root that implements
+// DirtyTracker.
+type DeltaSnapshotDatabase interface {
+	SnapshotDatabase
+	// It invokes Root.SnapshotDirty() and writes all its Writers into the
+	// WriteDeltaSnapshotRepository, with the last Snapshot taken as base.
+	TakeDeltaSnapshot(WriteDeltaSnapshotRepository) error
+}
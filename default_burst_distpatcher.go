@@ -12,9 +12,18 @@ func NewDefaultBurstDispatcher(repository WriteBurstRepository) *DefaultBurstDis
 	return &DefaultBurstDispatcher{nil, repository}
 }
 
-// Implements BurstDispatcher.Write().
+// Implements BurstDispatcher.Write(). Before it starts a new Burst, it returns
+// an *ErrStaleTransactionId if the Transaction is not above the high-water
+// mark of the WriteBurstRepository, if it implements HighWaterMarker.
 func (bd *DefaultBurstDispatcher) Write(transaction Transaction) (err error) {
 	if bd.burst == nil {
+		var mark TransactionId
+		if mark, err = bd.HighWaterMark(); err != nil {
+			return
+		}
+		if transaction.Id <= mark {
+			return &ErrStaleTransactionId{transaction.Id, mark}
+		}
 		bd.burst, err = bd.repository.WriteBurst()
 		if err != nil {
 			return
@@ -23,6 +32,24 @@ func (bd *DefaultBurstDispatcher) Write(transaction Transaction) (err error) {
 	return bd.burst.Write(transaction)
 }
 
+// Implements LockBurstDispatcher.Lock(). It does nothing if the
+// WriteBurstRepository does not implement LockRepository.
+func (bd *DefaultBurstDispatcher) Lock() error {
+	if repository, ok := bd.repository.(LockRepository); ok {
+		return repository.Lock()
+	}
+	return nil
+}
+
+// Implements HighWaterMarker.HighWaterMark(). It is zero if the
+// WriteBurstRepository does not implement it.
+func (bd *DefaultBurstDispatcher) HighWaterMark() (TransactionId, error) {
+	if marker, ok := bd.repository.(HighWaterMarker); ok {
+		return marker.HighWaterMark()
+	}
+	return 0, nil
+}
+
 // Implements BurstDispatcher.Rotate().
 func (bd *DefaultBurstDispatcher) Rotate() (err error) {
 	if bd.burst == nil {
@@ -35,12 +62,29 @@ func (bd *DefaultBurstDispatcher) Rotate() (err error) {
 	return
 }
 
-// Implements BurstDispatcher.Close().
+// Implements Syncer.Sync(). It syncs the current Burst if its BurstWriter
+// implements Syncer, like the ones of a DirBurstRepository. Otherwise it closes
+// it like Rotate(), because the Transactions of a Burst are not recovered until
+// it is closed, and closing it flushes it to stable storage if the
+// WriteBurstRepository does.
+func (bd *DefaultBurstDispatcher) Sync() error {
+	if syncer, ok := bd.burst.(Syncer); ok {
+		return syncer.Sync()
+	}
+	return bd.Rotate()
+}
+
+// Implements BurstDispatcher.Close(). It releases the lock of the
+// WriteBurstRepository if it implements LockRepository.
 func (bd *DefaultBurstDispatcher) Close() (err error) {
-	if bd.burst == nil {
-		return
+	if bd.burst != nil {
+		err = bd.burst.Close()
+		bd.burst = nil
+	}
+	if repository, ok := bd.repository.(LockRepository); ok {
+		if err2 := repository.Unlock(); err == nil {
+			err = err2
+		}
 	}
-	err = bd.burst.Close()
-	bd.burst = nil
 	return
 }
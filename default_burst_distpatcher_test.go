@@ -13,6 +13,32 @@ func TestDefaultBurstDispatcherInterface(t *testing.T) {
 	}
 }
 
+func TestDefaultBurstDispatcherSync(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	dispatcher := NewDefaultBurstDispatcher(repository)
+	defer dispatcher.Close()
+	var i interface{} = dispatcher
+	if _, ok := i.(Syncer); !ok {
+		t.Error(i)
+	}
+
+	if err := dispatcher.Sync(); err != nil {
+		t.Error(err)
+	}
+	for id := TransactionId(1); id <= 2; id++ {
+		if err := dispatcher.Write(Transaction{id, &testWriter{10 + int(id)}}); err != nil {
+			t.Error(err)
+		}
+		if err := dispatcher.Sync(); err != nil {
+			t.Error(err)
+		}
+		if bursts, err := repository.Bursts(); len(bursts) != int(id) || err != nil {
+			t.Error(bursts, err)
+		}
+	}
+}
+
 func TestDefaultBurstDispatcherWrite(t *testing.T) {
 
 	repository := NewMemBurstRepository()
@@ -1,16 +1,33 @@
 package gobdb
 
-// A Database, WriteDatabase and SnapshotDatabase. No thread-safe.
+import (
+	"encoding/gob"
+	"errors"
+	"io/ioutil"
+)
+
+// A Database, WriteDatabase, SnapshotDatabase and DeltaSnapshotDatabase.
+// No thread-safe.
 type DefaultDatabase struct {
 	root       Root
 	lastId     TransactionId
 	dispatcher BurstDispatcher
+	encoder    *gob.Encoder
+	snapshotId TransactionId
+	checked    bool
+	writeAhead bool
+	latched    error
 }
 
 // New instance. The TransactionId is the last one that has been applied to the
 // Root. The BurstDispatcher is optional.
 func NewDefaultDatabase(root Root, lastId TransactionId, dispatcher BurstDispatcher) *DefaultDatabase {
-	return &DefaultDatabase{root, lastId, dispatcher}
+	return &DefaultDatabase{root, lastId, dispatcher, nil, 0, false, false, nil}
+}
+
+// The last TransactionId applied to the Root.
+func (db *DefaultDatabase) LastId() TransactionId {
+	return db.lastId
 }
 
 // Implements Database.Read().
@@ -18,8 +35,30 @@ func (db *DefaultDatabase) Read(reader Reader) interface{} {
 	return reader.Read(db.root)
 }
 
-// Implements WriteDatabase.Write().
+// Implements WriteDatabase.Write(). If there is a BurstDispatcher, the type of
+// the Writer must have been registered with RegisterWriter() and the Writer is
+// gob encoded before it is applied, the first error is an
+// *ErrUnregisteredWriter if that fails. On the first one, it invokes Open() and
+// returns its error as the first one. After the second error, the
+// DefaultDatabase is latched, see ErrLatched.
 func (db *DefaultDatabase) Write(writer Writer) (value interface{}, err1 error, err2 error) {
+	if err1 = db.Latched(); err1 != nil {
+		return
+	}
+	defer func() {
+		if err2 != nil {
+			db.latched = err2
+		}
+	}()
+	if err1 = db.Open(); err1 != nil {
+		return
+	}
+	if err1 = db.checkWriter(writer); err1 != nil {
+		return
+	}
+	if db.writeAhead && db.dispatcher != nil {
+		return db.writeAheadWrite(writer)
+	}
 	value, err1 = writer.Write(db.root)
 	if err1 != nil {
 		return
@@ -31,13 +70,147 @@ func (db *DefaultDatabase) Write(writer Writer) (value interface{}, err1 error,
 	return
 }
 
-// Implements SnapshotDatabase.TakeSnapshot().
+// It enables or disables the write-ahead mode. In that mode, Write() writes the
+// Transaction to the BurstDispatcher first, makes it durable with Sync() if it
+// implements Syncer, and then applies the Writer to the Root. If the
+// BurstDispatcher fails, the Root is not modified. If the Writer fails, it
+// writes and syncs an AbortedTransaction right after it.
+// A DefaultBurstDispatcher syncs its Burst in place if the BurstWriter
+// implements Syncer; otherwise every Sync() closes it, and MergeBursts() may be
+// used to join them afterwards.
+func (db *DefaultDatabase) SetWriteAhead(writeAhead bool) {
+	db.writeAhead = writeAhead
+}
+
+func (db *DefaultDatabase) writeAheadWrite(writer Writer) (value interface{}, err1 error, err2 error) {
+	id := db.lastId + 1
+	if err2 = db.dispatcher.Write(Transaction{id, writer}); err2 != nil {
+		return
+	}
+	if err2 = db.sync(); err2 != nil {
+		return
+	}
+	db.lastId = id
+	if value, err1 = writer.Write(db.root); err1 != nil {
+		db.lastId++
+		if err2 = db.dispatcher.Write(Transaction{db.lastId, &AbortedTransaction{id}}); err2 != nil {
+			return
+		}
+		err2 = db.sync()
+	}
+	return
+}
+
+// It syncs the BurstDispatcher if it implements Syncer.
+func (db *DefaultDatabase) sync() error {
+	if syncer, ok := db.dispatcher.(Syncer); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
+
+// It prepares the BurstDispatcher to be written, before any Writer is applied
+// to the Root; Write() invokes it the first time. If the BurstDispatcher
+// implements LockBurstDispatcher, it acquires its lock, so the error may be an
+// *ErrLocked. Then, if it implements HighWaterMarker, the error is an
+// *ErrStaleTransactionId if the last TransactionId is below its high-water
+// mark. It does nothing once it succeeds.
+func (db *DefaultDatabase) Open() error {
+	if db.checked {
+		return nil
+	}
+	if locker, ok := db.dispatcher.(LockBurstDispatcher); ok {
+		if err := locker.Lock(); err != nil {
+			return err
+		}
+	}
+	if marker, ok := db.dispatcher.(HighWaterMarker); ok {
+		mark, err := marker.HighWaterMark()
+		if err != nil {
+			return err
+		}
+		if db.lastId < mark {
+			return &ErrStaleTransactionId{db.lastId + 1, mark}
+		}
+	}
+	db.checked = true
+	return nil
+}
+
+// It verifies that the Writer is registered and gob encodable, if it is going
+// to be written to the BurstDispatcher.
+func (db *DefaultDatabase) checkWriter(writer Writer) error {
+	if db.dispatcher == nil {
+		return nil
+	}
+	if !isRegisteredWriter(writer) {
+		return &ErrUnregisteredWriter{writer, errors.New("type not registered with RegisterWriter()")}
+	}
+	if db.encoder == nil {
+		db.encoder = gob.NewEncoder(ioutil.Discard)
+	}
+	if err := db.encoder.Encode(&writer); err != nil {
+		db.encoder = nil
+		return &ErrUnregisteredWriter{writer, err}
+	}
+	return nil
+}
+
+// Implements SnapshotDatabase.TakeSnapshot(). If the Root implements
+// DirtyTracker, its modified state is cleared.
 func (db *DefaultDatabase) TakeSnapshot(snapshooter Snapshooter, repository WriteSnapshotRepository) error {
 
+	if err := db.Latched(); err != nil {
+		return err
+	}
+
 	writer, err := repository.WriteSnapshot(db.lastId)
 	if err != nil {
 		return err
 	}
+
+	snapshoot := func(write func(...Writer) error) error {
+		return snapshooter(db.root, write)
+	}
+	return db.writeSnapshot(writer, snapshoot)
+}
+
+// Implements DeltaSnapshotDatabase.TakeDeltaSnapshot(). The base is the last
+// Snapshot taken, or the one given to SetSnapshotBase(). Nothing is written if
+// there has been no Transaction since then.
+func (db *DefaultDatabase) TakeDeltaSnapshot(repository WriteDeltaSnapshotRepository) error {
+
+	if err := db.Latched(); err != nil {
+		return err
+	}
+
+	tracker, ok := db.root.(DirtyTracker)
+	if !ok {
+		return errors.New("gobdb: TakeDeltaSnapshot() of a Root that is not a DirtyTracker")
+	}
+	if db.snapshotId == 0 {
+		return errors.New("gobdb: TakeDeltaSnapshot() without a base Snapshot")
+	}
+	if db.snapshotId == db.lastId {
+		return nil
+	}
+
+	writer, err := repository.WriteDeltaSnapshot(db.snapshotId, db.lastId)
+	if err != nil {
+		return err
+	}
+	return db.writeSnapshot(writer, tracker.SnapshotDirty)
+}
+
+// It sets the TransactionId of the last Snapshot applied to the Root, the base
+// of the next delta Snapshot. The modified state of the Root must have been
+// cleared right after it was applied, as ApplyRecoveryPlan() does.
+func (db *DefaultDatabase) SetSnapshotBase(id TransactionId) {
+	db.snapshotId = id
+}
+
+// It writes the Writers given by the function to the SnapshotWriter.
+func (db *DefaultDatabase) writeSnapshot(writer SnapshotWriter, snapshoot func(func(...Writer) error) error) error {
 	defer writer.Close()
 
 	write := func(writers ...Writer) error {
@@ -49,9 +222,16 @@ func (db *DefaultDatabase) TakeSnapshot(snapshooter Snapshooter, repository Writ
 		return nil
 	}
 
-	if err := snapshooter(db.root, write); err != nil {
+	if err := snapshoot(write); err != nil {
 		return err
 	}
 
-	return writer.Close()
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if tracker, ok := db.root.(DirtyTracker); ok {
+		tracker.ClearDirty()
+	}
+	db.snapshotId = writer.Id()
+	return nil
 }
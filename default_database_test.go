@@ -1,6 +1,7 @@
 package gobdb
 
 import (
+	"encoding/gob"
 	"fmt"
 	"io"
 	"testing"
@@ -58,6 +59,9 @@ func TestDefaultDatabaseInterface(t *testing.T) {
 	if _, ok := i.(SnapshotDatabase); !ok {
 		t.Error(i)
 	}
+	if _, ok := i.(DeltaSnapshotDatabase); !ok {
+		t.Error(i)
+	}
 }
 
 func TestDefaultDatabaseEmpty(t *testing.T) {
@@ -286,3 +290,110 @@ func TestDefaultDatabaseTakeSnapshot(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+type testUnregisteredWriter struct {
+	Increment int
+}
+
+func (op *testUnregisteredWriter) Write(root Root) (interface{}, error) {
+	r := root.(*testRoot)
+	r.counter += op.Increment
+	return r.counter, nil
+}
+
+func TestDefaultDatabaseUnregisteredWriter(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	dispatcher := NewDefaultBurstDispatcher(repository)
+	defer dispatcher.Close()
+	root := &testRoot{}
+	database := NewDefaultDatabase(root, 0, dispatcher)
+
+	_, err1, err2 := database.Write(&testUnregisteredWriter{11})
+	if e, ok := err1.(*ErrUnregisteredWriter); !ok {
+		t.Error(err1)
+	} else if _, ok := e.Writer.(*testUnregisteredWriter); !ok {
+		t.Errorf("%#v", e.Writer)
+	}
+	if err2 != nil {
+		t.Error(err2)
+	}
+	if root.counter != 0 {
+		t.Error(root.counter)
+	}
+
+	result, err1, err2 := database.Write(&testWriter{12})
+	if err1 != nil {
+		t.Error(err1)
+	}
+	if err2 != nil {
+		t.Error(err2)
+	}
+	if value, ok := result.(int); !ok {
+		t.Error(result)
+	} else if value != 12 {
+		t.Error(value)
+	}
+
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(bursts) != 1 || bursts[0].First() != 1 || bursts[0].Last() != 1 {
+		t.Error(bursts)
+	}
+}
+
+// A Writer registered with gob.Register() only.
+type testGobWriter struct {
+	Increment int
+}
+
+func (op *testGobWriter) Write(root Root) (interface{}, error) {
+	r := root.(*testRoot)
+	r.counter += op.Increment
+	return r.counter, nil
+}
+
+func init() {
+	gob.Register(&testGobWriter{})
+}
+
+func TestDefaultDatabaseGobWriter(t *testing.T) {
+
+	dispatcher := NewDefaultBurstDispatcher(NewMemBurstRepository())
+	defer dispatcher.Close()
+	root := &testRoot{}
+	database := NewDefaultDatabase(root, 0, dispatcher)
+
+	_, err1, err2 := database.Write(&testGobWriter{11})
+	if _, ok := err1.(*ErrUnregisteredWriter); !ok {
+		t.Error(err1)
+	}
+	if err2 != nil {
+		t.Error(err2)
+	}
+	if root.counter != 0 {
+		t.Error(root.counter)
+	}
+}
+
+func TestDefaultDatabaseUnregisteredWriterWithoutDispatcher(t *testing.T) {
+
+	root := &testRoot{}
+	database := NewDefaultDatabase(root, 0, nil)
+
+	_, err1, err2 := database.Write(&testUnregisteredWriter{11})
+	if err1 != nil {
+		t.Error(err1)
+	}
+	if err2 != nil {
+		t.Error(err2)
+	}
+	if root.counter != 11 || database.LastId() != 1 {
+		t.Error(root.counter, database.LastId())
+	}
+}
@@ -0,0 +1,202 @@
+package gobdb
+
+import (
+	"context"
+	"testing"
+)
+
+// A Root that tracks the modified keys.
+type testDirtyRoot struct {
+	values map[string]int
+	dirty  map[string]bool
+}
+
+func newTestDirtyRoot() *testDirtyRoot {
+	return &testDirtyRoot{make(map[string]int), make(map[string]bool)}
+}
+
+func (r *testDirtyRoot) SnapshotDirty(write func(...Writer) error) error {
+	for key := range r.dirty {
+		if err := write(&testSetWriter{key, r.values[key]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *testDirtyRoot) ClearDirty() {
+	r.dirty = make(map[string]bool)
+}
+
+type testSetWriter struct {
+	Key   string
+	Value int
+}
+
+func (op *testSetWriter) Write(root Root) (interface{}, error) {
+	r := root.(*testDirtyRoot)
+	r.values[op.Key] = op.Value
+	r.dirty[op.Key] = true
+	return nil, nil
+}
+
+func testDirtySnapshooter(root Root, write func(...Writer) error) error {
+	r := root.(*testDirtyRoot)
+	for key, value := range r.values {
+		if err := write(&testSetWriter{key, value}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	RegisterWriter(&testSetWriter{})
+}
+
+func TestDefaultDatabaseTakeDeltaSnapshot(t *testing.T) {
+
+	bursts := NewMemBurstRepository()
+	dispatcher := NewDefaultBurstDispatcher(bursts)
+	snapshots := NewMemSnapshotRepository()
+	database := NewDefaultDatabase(newTestDirtyRoot(), 0, dispatcher)
+
+	if err := database.TakeDeltaSnapshot(snapshots); err == nil {
+		t.Error(err)
+	}
+	if _, err1, err2 := database.Write(&testSetWriter{"a", 1}); err1 != nil || err2 != nil {
+		t.Error(err1, err2)
+	}
+	if _, err1, err2 := database.Write(&testSetWriter{"b", 2}); err1 != nil || err2 != nil {
+		t.Error(err1, err2)
+	}
+	if err := database.TakeSnapshot(testDirtySnapshooter, snapshots); err != nil {
+		t.Error(err)
+	}
+	if _, err1, err2 := database.Write(&testSetWriter{"a", 3}); err1 != nil || err2 != nil {
+		t.Error(err1, err2)
+	}
+	if err := database.TakeDeltaSnapshot(snapshots); err != nil {
+		t.Error(err)
+	}
+	if err := database.TakeDeltaSnapshot(snapshots); err != nil {
+		t.Error(err)
+	}
+	if _, err1, err2 := database.Write(&testSetWriter{"c", 4}); err1 != nil || err2 != nil {
+		t.Error(err1, err2)
+	}
+	if err := database.TakeDeltaSnapshot(snapshots); err != nil {
+		t.Error(err)
+	}
+	if _, err1, err2 := database.Write(&testSetWriter{"b", 5}); err1 != nil || err2 != nil {
+		t.Error(err1, err2)
+	}
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+
+	fulls, err := snapshots.Snapshots()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(fulls) != 1 || fulls[0].Id() != 2 {
+		t.Error(fulls)
+	}
+	deltas, err := snapshots.DeltaSnapshots()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(deltas) != 2 {
+		t.Error(deltas)
+	}
+
+	chain := SnapshotChain(2, 5, deltas)
+	if len(chain) != 2 || chain[0].Id() != 3 || chain[1].Id() != 4 {
+		t.Error(chain)
+	}
+	if base := chain[1].(DeltaSnapshotId).Base(); base != 3 {
+		t.Error(base)
+	}
+
+	burstIds, err := bursts.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	plan, err := PlanRecovery(0, append(fulls, deltas...), burstIds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Snapshot.Id() != 2 || len(plan.Deltas) != 2 || plan.LastId != 4 || plan.Target != 5 {
+		t.Error(plan)
+	}
+
+	root := newTestDirtyRoot()
+	var id TransactionId
+	if err := ApplyRecoveryPlan(context.Background(), root, plan, &id, nil); err != nil {
+		t.Error(err)
+	}
+	if id != 5 {
+		t.Error(id)
+	}
+	if root.values["a"] != 3 || root.values["b"] != 5 || root.values["c"] != 4 || len(root.values) != 3 {
+		t.Error(root.values)
+	}
+	if !root.dirty["b"] || len(root.dirty) != 1 {
+		t.Error(root.dirty)
+	}
+
+	database = NewDefaultDatabase(root, id, nil)
+	database.SetSnapshotBase(plan.LastId)
+	if err := database.TakeDeltaSnapshot(snapshots); err != nil {
+		t.Error(err)
+	}
+	deltas, err = snapshots.DeltaSnapshots()
+	if err != nil {
+		t.Error(err)
+	}
+	if chain := SnapshotChain(2, 5, deltas); len(chain) != 3 || chain[2].Id() != 5 {
+		t.Error(chain)
+	}
+}
+
+func TestSnapshotChainFurthest(t *testing.T) {
+
+	snapshots := NewMemSnapshotRepository()
+	for _, ids := range [][2]TransactionId{{2, 3}, {3, 5}, {2, 4}, {4, 5}} {
+		writer, err := snapshots.WriteDeltaSnapshot(ids[0], ids[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+	deltas, err := snapshots.DeltaSnapshots()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if chain := SnapshotChain(2, 5, deltas); len(chain) != 2 || chain[0].Id() != 3 && chain[0].Id() != 4 || chain[1].Id() != 5 {
+		t.Error(chain)
+	}
+	if chain := SnapshotChain(2, 4, deltas); len(chain) != 1 || chain[0].Id() != 4 {
+		t.Error(chain)
+	}
+	if chain := SnapshotChain(3, 4, deltas); len(chain) != 0 {
+		t.Error(chain)
+	}
+
+	writer, err := snapshots.WriteDeltaSnapshot(2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Error(err)
+	}
+	if deltas, err = snapshots.DeltaSnapshots(); err != nil {
+		t.Error(err)
+	}
+	if chain := SnapshotChain(2, 5, deltas); len(chain) != 1 || chain[0].Id() != 5 {
+		t.Error(chain)
+	}
+}
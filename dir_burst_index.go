@@ -0,0 +1,186 @@
+package gobdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// The sidecar file of a Burst written by a DirBurstRepository with an index
+// interval. A gob stream only sends the definition of every type once, so the
+// Transactions that introduced new types before a checkpoint must be decoded
+// before the ones at the checkpoint.
+type dirBurstIndex struct {
+	TypeDefs    []dirBurstIndexTypeDef
+	Checkpoints []dirBurstIndexCheckpoint
+}
+
+// The messages of a gob stream of a Transaction with a new type of Writer.
+type dirBurstIndexTypeDef struct {
+	Offset  int64
+	Message []byte
+}
+
+// The offset of the messages of a Transaction in a gob stream.
+type dirBurstIndexCheckpoint struct {
+	Id     TransactionId
+	Offset int64
+}
+
+// An io.Writer that builds the dirBurstIndex of the gob stream written to it.
+type dirBurstIndexWriter struct {
+	writer   io.Writer
+	interval int
+	index    dirBurstIndex
+	offset   int64
+	start    int64
+	message  []byte
+	types    map[reflect.Type]bool
+	count    int
+}
+
+func newDirBurstIndexWriter(writer io.Writer, interval int) *dirBurstIndexWriter {
+	return &dirBurstIndexWriter{writer: writer, interval: interval, types: make(map[reflect.Type]bool)}
+}
+
+func (w *dirBurstIndexWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	if w.message != nil {
+		w.message = append(w.message, p[:n]...)
+	}
+	w.offset += int64(n)
+	return n, err
+}
+
+// It must be invoked before a Transaction is encoded.
+func (w *dirBurstIndexWriter) begin(transaction Transaction) {
+	w.start = w.offset
+	w.message = nil
+	if !w.types[reflect.TypeOf(transaction.Writer)] {
+		w.message = []byte{}
+	}
+}
+
+// It must be invoked after a Transaction is encoded successfully.
+func (w *dirBurstIndexWriter) end(transaction Transaction) {
+	if w.message != nil {
+		w.index.TypeDefs = append(w.index.TypeDefs, dirBurstIndexTypeDef{w.start, w.message})
+		w.types[reflect.TypeOf(transaction.Writer)] = true
+		w.message = nil
+	}
+	if w.count%w.interval == 0 {
+		w.index.Checkpoints = append(w.index.Checkpoints, dirBurstIndexCheckpoint{transaction.Id, w.start})
+	}
+	w.count++
+}
+
+func (id *dirBurstId) indexPath() string {
+	return id.path() + ".index"
+}
+
+func (id *dirBurstId) writeIndex(index *dirBurstIndex) error {
+	fs := id.repository.fs
+	file, err := fs.CreateTemp(id.repository.dir, "tmp-index-")
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	err1 := gob.NewEncoder(writer).Encode(index)
+	err2 := writer.Flush()
+	err3 := file.Close()
+	for _, err := range []error{err1, err2, err3} {
+		if err != nil {
+			fs.Remove(file.Name())
+			return err
+		}
+	}
+	return fs.Rename(file.Name(), id.indexPath())
+}
+
+func (id *dirBurstId) readIndex() (*dirBurstIndex, error) {
+	file, err := id.repository.fs.Open(id.indexPath())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	index := &dirBurstIndex{}
+	if err := gob.NewDecoder(bufio.NewReader(file)).Decode(index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// Implements SeekBurstId.ReadFrom(). Without an index file, it reads the Burst
+// from the beginning.
+func (id *dirBurstId) ReadFrom(from TransactionId) (BurstReader, error) {
+
+	index, err := id.readIndex()
+	if err != nil {
+		return id.Read()
+	}
+	checkpoints := index.Checkpoints
+	k := sort.Search(len(checkpoints), func(i int) bool {
+		return checkpoints[i].Id > from
+	}) - 1
+	if k < 0 || checkpoints[k].Offset == 0 {
+		return id.Read()
+	}
+	checkpoint := checkpoints[k]
+
+	file, err := id.repository.fs.Open(id.path())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(checkpoint.Offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	var prefix bytes.Buffer
+	for _, typeDef := range index.TypeDefs {
+		if typeDef.Offset < checkpoint.Offset {
+			prefix.Write(typeDef.Message)
+		}
+	}
+	counter := &countingReader{file, 0}
+	decoder := gob.NewDecoder(bufio.NewReader(io.MultiReader(&prefix, counter)))
+	reader := &dirSeekBurstReader{&dirBurstReader{file, counter, decoder, id, 0}, checkpoint.Id - 1, false}
+	return reader, nil
+}
+
+// A dirBurstReader that started at a checkpoint, after the Transactions with
+// the type definitions, that are skipped. The type definitions nested in the
+// Writers of other Transactions before the checkpoint are not in the index,
+// so on any error it reads the Burst again from the beginning.
+type dirSeekBurstReader struct {
+	*dirBurstReader
+	last     TransactionId
+	fallback bool
+}
+
+func (br *dirSeekBurstReader) Read() (Transaction, error) {
+	transaction, err := br.dirBurstReader.Read()
+	for err == nil && transaction.Id <= br.last {
+		transaction, err = br.dirBurstReader.Read()
+	}
+	if err != nil && err != io.EOF && !br.fallback {
+		br.fallback = true
+		br.dirBurstReader.Close()
+		reader, err2 := br.mid.Read()
+		if err2 != nil {
+			return Transaction{}, err2
+		}
+		br.dirBurstReader = reader.(*dirBurstReader)
+		for {
+			if transaction, err = br.dirBurstReader.Read(); err != nil || transaction.Id > br.last {
+				break
+			}
+		}
+	}
+	if err == nil {
+		br.last = transaction.Id
+	}
+	return transaction, err
+}
@@ -0,0 +1,175 @@
+package gobdb
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// A Writer with a nested interface, whose type definitions are not at the top
+// level of the gob stream.
+type testNestedWriter struct {
+	Value interface{}
+}
+
+type testNestedValue struct {
+	Increment int
+}
+
+type testOtherNestedValue struct {
+	Increment int
+}
+
+func (op *testNestedWriter) Write(root Root) (interface{}, error) {
+	r := root.(*testRoot)
+	switch value := op.Value.(type) {
+	case *testNestedValue:
+		r.counter += value.Increment
+	case *testOtherNestedValue:
+		r.counter += value.Increment
+	}
+	return r.counter, nil
+}
+
+func init() {
+	RegisterWriter(&testNestedWriter{})
+	gob.Register(&testNestedValue{})
+	gob.Register(&testOtherNestedValue{})
+}
+
+func testWriteIndexedBurst(t *testing.T, repository *DirBurstRepository, writers ...Writer) {
+	wburst, err := repository.WriteBurst()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, writer := range writers {
+		if err := wburst.Write(Transaction{TransactionId(i + 1), writer}); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := wburst.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDirBurstIndex(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repository := NewDirBurstRepository(dir)
+	repository.SetIndexInterval(3)
+	testWriteIndexedBurst(t, repository,
+		&testWriter{1}, &testWriter{2}, &testWriter{3}, &testWriter{4}, &testWriter{5},
+		&testSetWriter{"a", 6}, &testWriter{7}, &testWriter{8}, &testWriter{9}, &testWriter{10})
+
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(bursts) != 1 {
+		t.Fatal(bursts)
+	}
+	id, ok := bursts[0].(SeekBurstId)
+	if !ok {
+		t.Fatal(bursts[0])
+	}
+
+	for from, expected := range map[TransactionId]TransactionId{1: 1, 3: 1, 4: 4, 6: 4, 7: 7, 9: 7, 10: 10, 11: 10} {
+		reader, err := id.ReadFrom(from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous := expected - 1
+		for {
+			transaction, err := reader.Read()
+			if err != nil {
+				break
+			}
+			if transaction.Id != previous+1 {
+				t.Error(from, transaction.Id)
+			}
+			previous = transaction.Id
+		}
+		if previous != 10 {
+			t.Error(from, previous)
+		}
+		if err := reader.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	root := &testRoot{1 + 2 + 3 + 4 + 5}
+	var last TransactionId
+	if err := ApplyBursts(root, 6, &last, bursts); err != nil {
+		t.Error(err)
+	}
+	if last != 10 {
+		t.Error(last)
+	}
+	if root.counter != 49 {
+		t.Error(root.counter)
+	}
+}
+
+func TestDirBurstIndexNested(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repository := NewDirBurstRepository(dir)
+	repository.SetIndexInterval(2)
+	testWriteIndexedBurst(t, repository,
+		&testNestedWriter{&testNestedValue{1}}, &testNestedWriter{&testOtherNestedValue{2}},
+		&testWriter{3}, &testNestedWriter{&testOtherNestedValue{4}})
+
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(bursts) != 1 {
+		t.Fatal(bursts)
+	}
+
+	reader, err := bursts[0].(SeekBurstId).ReadFrom(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	previous := TransactionId(2)
+	for {
+		transaction, err := reader.Read()
+		if err != nil {
+			break
+		}
+		if transaction.Id != previous+1 {
+			t.Error(transaction.Id)
+		}
+		previous = transaction.Id
+	}
+	if previous != 4 {
+		t.Error(previous)
+	}
+	if !reader.(*dirSeekBurstReader).fallback {
+		t.Error(reader)
+	}
+
+	root := &testRoot{1 + 2}
+	var last TransactionId
+	if err := ApplyBursts(root, 2, &last, bursts); err != nil {
+		t.Error(err)
+	}
+	if last != 4 {
+		t.Error(last)
+	}
+	if root.counter != 10 {
+		t.Error(root.counter)
+	}
+}
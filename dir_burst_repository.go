@@ -5,61 +5,171 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
+	"io"
 	"path/filepath"
+	"sync"
 )
 
 const dirBurstRepositoryFileNameFormat = "burst-%d-%d.gobdb"
 const dirBurstRepositoryFileNameScanFormat = dirBurstRepositoryFileNameFormat + "\n"
+const dirBurstRepositoryOpenFileNameFormat = "burst-%d-open.gobdb"
+const dirBurstRepositoryOpenFileNameScanFormat = dirBurstRepositoryOpenFileNameFormat + "\n"
 
-// A BurstRepository and WriteBurstRepository that uses one file per Burst.
+// A BurstRepository, WriteBurstRepository, RemoveBurstRepository and
+// LockRepository that uses one file per Burst. WriteBurst() and RemoveBurst()
+// acquire the lock of the directory, so that only one DirBurstRepository
+// writes to it; reading does not. Its BurstWriters implement Syncer, and a
+// Burst that was synced but not closed, because of a crash, is listed up to
+// its last complete Transaction.
 // Thread-safe, but BurstReaders and BurstWriters are not.
 type DirBurstRepository struct {
-	dir string
+	dir           string
+	fs            FileSystem
+	indexInterval int
+	locker        *dirLocker
+	written       *highWaterMark
+	// held while a Burst and its index file are renamed or removed
+	files *sync.Mutex
+	// the unclosed Bursts of its own BurstWriters, not listed
+	writing map[string]bool
 }
 
 func NewDirBurstRepository(dir string) *DirBurstRepository {
-	return &DirBurstRepository{dir}
+	return NewDirBurstRepositoryFS(dir, OSFileSystem{})
+}
+
+// New instance on the given FileSystem.
+func NewDirBurstRepositoryFS(dir string, fs FileSystem) *DirBurstRepository {
+	locker := &dirLocker{fs: fs, path: filepath.Join(dir, dirBurstRepositoryLockName)}
+	return &DirBurstRepository{dir, fs, 0, locker, &highWaterMark{}, &sync.Mutex{}, map[string]bool{}}
+}
+
+// Implements LockRepository.Lock(). It returns an *ErrLocked if another
+// DirBurstRepository holds it, in this process or another one.
+func (r *DirBurstRepository) Lock() error {
+	return r.locker.lock()
+}
+
+// Implements LockRepository.Unlock().
+func (r *DirBurstRepository) Unlock() error {
+	r.written.forget()
+	return r.locker.unlock()
+}
+
+// It enables the index files of the next Bursts written, with a checkpoint
+// every given number of Transactions, so that their BurstIds implement
+// SeekBurstId efficiently. Zero disables them. It is not thread-safe.
+func (r *DirBurstRepository) SetIndexInterval(interval int) {
+	r.indexInterval = interval
 }
 
 func (r *DirBurstRepository) Bursts() ([]BurstId, error) {
-	file, err := os.Open(r.dir)
+	infos, err := r.fs.ReadDir(r.dir)
 	if err != nil {
 		return nil, err
 	}
-	infos, err1 := file.Readdir(-1)
-	err2 := file.Close()
-	if err1 != nil {
-		return nil, err1
-	}
-	if err2 != nil {
-		return nil, err2
-	}
 	ids := make([]BurstId, 0, len(infos))
+	closed, open := map[TransactionId]bool{}, []TransactionId{}
 	for _, info := range infos {
 		name := info.Name()
 		var first, last int
 		if n, err := fmt.Sscanf(name, dirBurstRepositoryFileNameScanFormat, &first, &last); n == 2 && err == nil {
-			ids = append(ids, &dirBurstId{TransactionId(first), TransactionId(last), r})
+			ids = append(ids, &dirBurstId{TransactionId(first), TransactionId(last), r, false})
+			closed[TransactionId(first)] = true
+		} else if n, err := fmt.Sscanf(name, dirBurstRepositoryOpenFileNameScanFormat, &first); n == 1 && err == nil {
+			open = append(open, TransactionId(first))
+		}
+	}
+	for _, first := range open {
+		id := &dirBurstId{first, 0, r, true}
+		r.files.Lock()
+		writing := r.writing[id.path()]
+		r.files.Unlock()
+		// a crash after the Burst was renamed leaves both of them
+		if writing || closed[first] {
+			continue
+		}
+		last, err := id.scanLast()
+		if err != nil {
+			return nil, err
+		}
+		if last != 0 {
+			id.last = last
+			ids = append(ids, id)
 		}
 	}
 	return ids, nil
 }
 
+// Implements HighWaterMarker.HighWaterMark(). The directory is listed once
+// while the lock is held, and every time otherwise.
+func (r *DirBurstRepository) HighWaterMark() (TransactionId, error) {
+	if mark, ok := r.written.getListed(); ok {
+		return mark, nil
+	}
+	held := r.locker.held()
+	ids, err := r.Bursts()
+	if err != nil {
+		return 0, err
+	}
+	var mark TransactionId
+	for _, id := range ids {
+		if id.Last() > mark {
+			mark = id.Last()
+		}
+	}
+	if !held {
+		r.written.update(mark)
+		return r.written.get(), nil
+	}
+	return r.written.updateListed(mark), nil
+}
+
 func (r *DirBurstRepository) WriteBurst() (BurstWriter, error) {
-	file, err := ioutil.TempFile(r.dir, "tmp-burst-")
+	if err := r.Lock(); err != nil {
+		return nil, err
+	}
+	file, err := r.fs.CreateTemp(r.dir, "tmp-burst-")
 	if err != nil {
 		return nil, err
 	}
 	writer := bufio.NewWriter(file)
-	encoder := gob.NewEncoder(writer)
-	return &dirBurstWriter{file, writer, encoder, 0, 0, r}, nil
+	bw := &dirBurstWriter{file, writer, nil, 0, 0, r, nil, ""}
+	if r.indexInterval > 0 {
+		bw.index = newDirBurstIndexWriter(writer, r.indexInterval)
+		bw.encoder = gob.NewEncoder(bw.index)
+	} else {
+		bw.encoder = gob.NewEncoder(writer)
+	}
+	return bw, nil
+}
+
+// Implements RemoveBurstRepository.RemoveBurst(). It acquires the lock of the
+// directory like WriteBurst(), and it removes the index file too.
+func (r *DirBurstRepository) RemoveBurst(id BurstId) error {
+	if err := r.Lock(); err != nil {
+		return err
+	}
+	did := &dirBurstId{id.First(), id.Last(), r, false}
+	if d, ok := id.(*dirBurstId); ok {
+		did.open = d.open
+	}
+	r.files.Lock()
+	defer r.files.Unlock()
+	if err := r.fs.Remove(did.path()); err != nil {
+		return err
+	}
+	if _, err := r.fs.Stat(did.indexPath()); err == nil {
+		return r.fs.Remove(did.indexPath())
+	}
+	return nil
 }
 
 type dirBurstId struct {
 	first, last TransactionId
 	repository  *DirBurstRepository
+	// synced but not closed
+	open bool
 }
 
 func (id *dirBurstId) First() TransactionId {
@@ -74,21 +184,57 @@ func (id *dirBurstId) Repository() BurstRepository {
 	return id.repository
 }
 
-func (id *dirBurstId) Read() (BurstReader, error) {
+func (id *dirBurstId) path() string {
 	name := fmt.Sprintf(dirBurstRepositoryFileNameFormat, id.first, id.last)
-	file, err := os.Open(filepath.Join(id.repository.dir, name))
+	if id.open {
+		name = fmt.Sprintf(dirBurstRepositoryOpenFileNameFormat, id.first)
+	}
+	return filepath.Join(id.repository.dir, name)
+}
+
+// The last complete Transaction of an unclosed Burst, zero if there is none.
+// The Writers are skipped, so their types do not need to be registered.
+func (id *dirBurstId) scanLast() (TransactionId, error) {
+	file, err := id.repository.fs.Open(id.path())
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	decoder := gob.NewDecoder(bufio.NewReader(file))
+	var last TransactionId
+	for {
+		var transaction struct{ Id TransactionId }
+		if err := decoder.Decode(&transaction); err != nil || transaction.Id <= last {
+			return last, nil
+		}
+		last = transaction.Id
+	}
+}
+
+func (id *dirBurstId) Size() (int64, error) {
+	info, err := id.repository.fs.Stat(id.path())
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (id *dirBurstId) Read() (BurstReader, error) {
+	file, err := id.repository.fs.Open(id.path())
 	if err != nil {
 		return nil, err
 	}
-	reader := bufio.NewReader(file)
-	decoder := gob.NewDecoder(reader)
-	return &dirBurstReader{file, decoder, id}, nil
+	counter := &countingReader{file, 0}
+	decoder := gob.NewDecoder(bufio.NewReader(counter))
+	return &dirBurstReader{file, counter, decoder, id, 0}, nil
 }
 
 type dirBurstReader struct {
-	file    *os.File
+	file    File
+	counter *countingReader
 	decoder *gob.Decoder
 	mid     *dirBurstId
+	last    TransactionId
 }
 
 func (br *dirBurstReader) Id() BurstId {
@@ -96,21 +242,44 @@ func (br *dirBurstReader) Id() BurstId {
 }
 
 func (br *dirBurstReader) Read() (Transaction, error) {
+	transaction, err := br.readRaw()
+	if err == nil {
+		transaction.Writer, err = migrateWriter(transaction.Writer)
+	}
+	return transaction, err
+}
+
+// Implements rawBurstReader.readRaw(). An unclosed Burst ends at the last
+// Transaction of its BurstId, before any incomplete one.
+func (br *dirBurstReader) readRaw() (Transaction, error) {
 	var transaction Transaction
+	if br.mid.open && br.last == br.mid.last {
+		return transaction, io.EOF
+	}
 	err := br.decoder.Decode(&transaction)
+	if err == nil {
+		br.last = transaction.Id
+	}
 	return transaction, err
 }
 
+func (br *dirBurstReader) BytesRead() int64 {
+	return br.counter.count
+}
+
 func (br *dirBurstReader) Close() error {
 	return br.file.Close()
 }
 
 type dirBurstWriter struct {
-	file        *os.File
+	file        File
 	writer      *bufio.Writer
 	encoder     *gob.Encoder
 	first, last TransactionId
 	repository  *DirBurstRepository
+	index       *dirBurstIndexWriter
+	// the link made by Sync(), if any
+	open string
 }
 
 func (bw *dirBurstWriter) First() TransactionId {
@@ -128,24 +297,77 @@ func (bw *dirBurstWriter) Write(transaction Transaction) error {
 	if bw.encoder == nil {
 		return errors.New("gobdb: write() on closed BurstWriter")
 	}
+	if bw.index != nil {
+		bw.index.begin(transaction)
+	}
 	err := bw.encoder.Encode(&transaction)
 	if err == nil {
 		if bw.first == 0 {
 			bw.first = transaction.Id
 		}
 		bw.last = transaction.Id
+		bw.repository.written.update(transaction.Id)
+		if bw.index != nil {
+			bw.index.end(transaction)
+		}
 	}
 	return err
 }
 
+// Implements Syncer.Sync(). It flushes the Transactions written so far to
+// stable storage without closing the Burst. The first time, it links the file
+// with the name of an unclosed Burst, so that they are recovered after a crash.
+func (bw *dirBurstWriter) Sync() error {
+	if bw.encoder == nil {
+		return errors.New("gobdb: sync() on closed BurstWriter")
+	}
+	if bw.last == 0 {
+		return nil
+	}
+	if err := bw.writer.Flush(); err != nil {
+		return err
+	}
+	if err := bw.file.Sync(); err != nil {
+		return err
+	}
+	if bw.open != "" {
+		return nil
+	}
+	fs, id := bw.repository.fs, &dirBurstId{bw.first, 0, bw.repository, true}
+	bw.repository.files.Lock()
+	defer bw.repository.files.Unlock()
+	// left by a crash before any of its Transactions was complete
+	if _, err := fs.Stat(id.path()); err == nil {
+		if err := fs.Remove(id.path()); err != nil {
+			return err
+		}
+	}
+	if err := fs.Link(bw.file.Name(), id.path()); err != nil {
+		return err
+	}
+	bw.open = id.path()
+	bw.repository.writing[bw.open] = true
+	return fs.SyncDir(bw.repository.dir)
+}
+
+// It renames the file to the name of a closed Burst and removes the link made
+// by Sync(), if any.
 func (bw *dirBurstWriter) Close() error {
 	if bw.encoder == nil {
 		return errors.New("gobdb: close() on closed BurstWriter")
 	}
+	bw.encoder = nil
+	if bw.open != "" {
+		defer func() {
+			bw.repository.files.Lock()
+			delete(bw.repository.writing, bw.open)
+			bw.repository.files.Unlock()
+		}()
+	}
 	oldname := bw.file.Name()
 	if bw.last == 0 {
 		err1 := bw.file.Close()
-		err2 := os.Remove(oldname)
+		err2 := bw.repository.fs.Remove(oldname)
 		if err1 != nil {
 			return err1
 		}
@@ -154,8 +376,11 @@ func (bw *dirBurstWriter) Close() error {
 		}
 		return nil
 	}
-	newname := fmt.Sprintf(dirBurstRepositoryFileNameFormat, bw.first, bw.last)
+	id := &dirBurstId{bw.first, bw.last, bw.repository, false}
 	err1 := bw.writer.Flush()
+	if err1 == nil {
+		err1 = bw.file.Sync()
+	}
 	err2 := bw.file.Close()
 	if err1 != nil {
 		return err1
@@ -163,5 +388,21 @@ func (bw *dirBurstWriter) Close() error {
 	if err2 != nil {
 		return err2
 	}
-	return os.Rename(oldname, filepath.Join(bw.repository.dir, newname))
+	bw.repository.files.Lock()
+	defer bw.repository.files.Unlock()
+	if err := bw.repository.fs.Rename(oldname, id.path()); err != nil {
+		return err
+	}
+	if bw.open != "" {
+		if err := bw.repository.fs.Remove(bw.open); err != nil {
+			return err
+		}
+	}
+	if err := bw.repository.fs.SyncDir(bw.repository.dir); err != nil {
+		return err
+	}
+	if bw.index != nil {
+		return id.writeIndex(&bw.index.index)
+	}
+	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -177,7 +178,7 @@ func TestDirBurstRepositoryBursts(t *testing.T) {
 		t.Fatal(rburst)
 	}
 	if rburst.Id() != id {
-		t.Error(rburst.Id)
+		t.Error(rburst.Id())
 	}
 	defer rburst.Close()
 
@@ -215,3 +216,77 @@ func TestDirBurstRepositoryBursts(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestDirBurstRepositorySync(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repository := NewDirBurstRepository(dir)
+	wburst, err := repository.WriteBurst()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wburst.Close()
+	syncer, ok := wburst.(Syncer)
+	if !ok {
+		t.Fatal(wburst)
+	}
+	for id := TransactionId(1); id <= 2; id++ {
+		if err := wburst.Write(Transaction{id, &testWriter{10 + int(id)}}); err != nil {
+			t.Error(err)
+		}
+		if err := syncer.Sync(); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := wburst.Write(Transaction{3, &testWriter{13}}); err != nil {
+		t.Error(err)
+	}
+
+	// the unclosed Burst is not listed by its own repository
+	if bursts, err := repository.Bursts(); err != nil || len(bursts) != 0 {
+		t.Error(bursts, err)
+	}
+	bursts, err := NewDirBurstRepository(dir).Bursts()
+	if err != nil || len(bursts) != 1 || bursts[0].First() != 1 || bursts[0].Last() != 2 {
+		t.Fatal(bursts, err)
+	}
+	root := &testRoot{}
+	var id TransactionId
+	if err := ApplyBursts(root, 0, &id, bursts); err != nil || id != 2 || root.counter != 23 {
+		t.Error(id, root.counter, err)
+	}
+
+	// an incomplete Transaction at the end is ignored
+	if err := syncer.Sync(); err != nil {
+		t.Error(err)
+	}
+	name := filepath.Join(dir, "burst-1-open.gobdb")
+	if info, err := os.Stat(name); err != nil {
+		t.Error(err)
+	} else if err := os.Truncate(name, info.Size()-1); err != nil {
+		t.Error(err)
+	}
+	bursts, err = NewDirBurstRepository(dir).Bursts()
+	if err != nil || len(bursts) != 1 || bursts[0].Last() != 2 {
+		t.Fatal(bursts, err)
+	}
+	root, id = &testRoot{}, 0
+	if err := ApplyBursts(root, 0, &id, bursts); err != nil || id != 2 || root.counter != 23 {
+		t.Error(id, root.counter, err)
+	}
+
+	if err := wburst.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Error(err)
+	}
+	if bursts, err := NewDirBurstRepository(dir).Bursts(); err != nil || len(bursts) != 1 || bursts[0].Last() != 3 {
+		t.Error(bursts, err)
+	}
+}
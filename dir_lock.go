@@ -0,0 +1,113 @@
+package gobdb
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+const dirBurstRepositoryLockName = "burst.lock"
+const dirSnapshotRepositoryLockName = "snapshot.lock"
+
+// An optional interface of WriteBurstRepositories and WriteSnapshotRepositories
+// that hold an exclusive lock while they are written.
+type LockRepository interface {
+	// It acquires the lock if it is not held yet.
+	Lock() error
+	// It releases the lock if it is held.
+	Unlock() error
+}
+
+// The error returned when a repository is locked by another writer.
+type ErrLocked struct {
+	Path string
+	// The process that holds the lock, or zero if unknown.
+	Pid int
+}
+
+func (e *ErrLocked) Error() string {
+	return fmt.Sprintf("gobdb: %s is locked by pid %d", e.Path, e.Pid)
+}
+
+// An optional interface of BurstDispatchers whose WriteBurstRepositories hold
+// an exclusive lock while they are written.
+type LockBurstDispatcher interface {
+	// It acquires the lock of the WriteBurstRepository if it is not held yet.
+	Lock() error
+}
+
+// The lock of a directory, held while its repository writes to it: from
+// lock() to unlock(), and from acquire() to the last release().
+// Thread-safe.
+type dirLocker struct {
+	mutex    sync.Mutex
+	fs       FileSystem
+	path     string
+	closer   io.Closer
+	locked   bool
+	acquired int
+}
+
+func (l *dirLocker) lock() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if err := l.hold(); err != nil {
+		return err
+	}
+	l.locked = true
+	return nil
+}
+
+func (l *dirLocker) unlock() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.locked = false
+	return l.free()
+}
+
+func (l *dirLocker) acquire() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if err := l.hold(); err != nil {
+		return err
+	}
+	l.acquired++
+	return nil
+}
+
+func (l *dirLocker) release() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.acquired--
+	return l.free()
+}
+
+// It returns whether the lock file is held.
+func (l *dirLocker) held() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.closer != nil
+}
+
+// It takes the lock file if it is not held.
+func (l *dirLocker) hold() error {
+	if l.closer != nil {
+		return nil
+	}
+	closer, err := l.fs.Lock(l.path)
+	if err != nil {
+		return err
+	}
+	l.closer = closer
+	return nil
+}
+
+// It closes the lock file if it is held and nothing needs it.
+func (l *dirLocker) free() error {
+	if l.closer == nil || l.locked || l.acquired > 0 {
+		return nil
+	}
+	err := l.closer.Close()
+	l.closer = nil
+	return err
+}
@@ -0,0 +1,174 @@
+package gobdb
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDirRepositoryLockInterface(t *testing.T) {
+
+	var i interface{} = NewDirBurstRepository("")
+	if _, ok := i.(LockRepository); !ok {
+		t.Error(i)
+	}
+	i = NewDirSnapshotRepository("")
+	if _, ok := i.(LockRepository); !ok {
+		t.Error(i)
+	}
+}
+
+func TestDirBurstRepositoryLock(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repository1 := NewDirBurstRepository(dir)
+	repository2 := NewDirBurstRepository(dir)
+	dispatcher := NewDefaultBurstDispatcher(repository1)
+	if err := dispatcher.Write(Transaction{1, &testWriter{1}}); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := repository2.WriteBurst(); err == nil {
+		t.Error(err)
+	} else if locked, ok := err.(*ErrLocked); !ok || locked.Pid != os.Getpid() {
+		t.Error(err)
+	}
+	if err := repository2.Lock(); err == nil {
+		t.Error(err)
+	}
+
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+	bursts, err := repository2.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(bursts) != 1 {
+		t.Error(bursts)
+	}
+
+	testWriteBurst(t, repository2, 2)
+	if err := repository1.Lock(); err == nil {
+		t.Error(err)
+	}
+	if err := repository2.Unlock(); err != nil {
+		t.Error(err)
+	}
+	if err := repository1.Lock(); err != nil {
+		t.Error(err)
+	}
+	if err := repository1.Unlock(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDirSnapshotRepositoryLock(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repository1 := NewDirSnapshotRepository(dir)
+	repository2 := NewDirSnapshotRepository(dir)
+	wsnapshot, err := repository1.WriteSnapshot(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repository2.WriteSnapshot(2); err == nil {
+		t.Error(err)
+	} else if _, ok := err.(*ErrLocked); !ok {
+		t.Error(err)
+	}
+
+	// the lock is released with the SnapshotWriter
+	if err := wsnapshot.Close(); err != nil {
+		t.Error(err)
+	}
+	wsnapshot, err = repository2.WriteSnapshot(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wsnapshot.Close(); err != nil {
+		t.Error(err)
+	}
+	if snapshots, err := repository2.Snapshots(); err != nil || len(snapshots) != 2 {
+		t.Error(snapshots, err)
+	}
+
+	// but not if it is held with Lock()
+	if err := repository1.Lock(); err != nil {
+		t.Error(err)
+	}
+	wsnapshot, err = repository1.WriteSnapshot(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wsnapshot.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err := repository2.WriteSnapshot(4); err == nil {
+		t.Error(err)
+	}
+
+	// the lock of the Bursts is independent
+	bursts := NewDirBurstRepository(dir)
+	if err := bursts.Lock(); err != nil {
+		t.Error(err)
+	}
+	if err := bursts.Unlock(); err != nil {
+		t.Error(err)
+	}
+
+	if err := repository1.Unlock(); err != nil {
+		t.Error(err)
+	}
+	if err := repository2.Lock(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDefaultDatabaseLockBeforeWrite(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dispatcher1 := NewNumTransactionsBurstDispatcher(10, NewDefaultBurstDispatcher(NewDirBurstRepository(dir)))
+	database1 := NewDefaultDatabase(&testRoot{}, 0, dispatcher1)
+	if err := database1.Open(); err != nil {
+		t.Error(err)
+	}
+
+	dispatcher2 := NewDefaultBurstDispatcher(NewDirBurstRepository(dir))
+	root2 := &testRoot{}
+	database2 := NewDefaultDatabase(root2, 0, dispatcher2)
+	if _, err1, err2 := database2.Write(&testWriter{1}); err2 != nil {
+		t.Error(err2)
+	} else if _, ok := err1.(*ErrLocked); !ok {
+		t.Error(err1)
+	}
+	if root2.counter != 0 || database2.LastId() != 0 {
+		t.Error(root2.counter, database2.LastId())
+	}
+
+	if err := dispatcher1.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err1, err2 := database2.Write(&testWriter{1}); err1 != nil || err2 != nil {
+		t.Error(err1, err2)
+	}
+	if err := dispatcher2.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,33 @@
+//go:build windows
+// +build windows
+
+package gobdb
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+// It acquires an exclusive LockFileEx without blocking. It returns false if
+// another open file holds it. It locks one byte far after the end of the file,
+// so that the pid in the file can still be read.
+func lockFile(file *os.File) (bool, error) {
+	overlapped := syscall.Overlapped{Offset: 0, OffsetHigh: 0x7fffffff}
+	r, _, err := procLockFileEx.Call(file.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if r != 0 {
+		return true, nil
+	}
+	if err == errorLockViolation {
+		return false, nil
+	}
+	return false, err
+}
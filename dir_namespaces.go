@@ -0,0 +1,76 @@
+package gobdb
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A directory that hosts several independent databases, one subdirectory per
+// name with its Bursts and Snapshots. Each one has its own sequence of
+// TransactionIds. Thread-safe.
+type DirNamespaces struct {
+	dir string
+	fs  FileSystem
+}
+
+// New instance.
+func NewDirNamespaces(dir string) *DirNamespaces {
+	return NewDirNamespacesFS(dir, OSFileSystem{})
+}
+
+// New instance on the given FileSystem, which the repositories share.
+func NewDirNamespacesFS(dir string, fs FileSystem) *DirNamespaces {
+	return &DirNamespaces{dir, fs}
+}
+
+// The names of the databases present, sorted.
+func (n *DirNamespaces) Namespaces() ([]string, error) {
+	infos, err := n.fs.ReadDir(n.dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if name := info.Name(); info.IsDir() && checkNamespace(name) == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// It returns the repositories of a database, creating its subdirectory if
+// needed and committing it to stable storage.
+func (n *DirNamespaces) Namespace(name string) (*DirBurstRepository, *DirSnapshotRepository, error) {
+	if err := checkNamespace(name); err != nil {
+		return nil, nil, err
+	}
+	dir := filepath.Join(n.dir, name)
+	if err := n.fs.MkdirAll(dir); err != nil {
+		return nil, nil, err
+	}
+	if err := n.fs.SyncDir(n.dir); err != nil {
+		return nil, nil, err
+	}
+	return NewDirBurstRepositoryFS(dir, n.fs), NewDirSnapshotRepositoryFS(dir, n.fs), nil
+}
+
+// It removes a database and all its Bursts and Snapshots.
+func (n *DirNamespaces) RemoveNamespace(name string) error {
+	if err := checkNamespace(name); err != nil {
+		return err
+	}
+	if err := n.fs.RemoveAll(filepath.Join(n.dir, name)); err != nil {
+		return err
+	}
+	return n.fs.SyncDir(n.dir)
+}
+
+func checkNamespace(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return errors.New("gobdb: invalid namespace " + name)
+	}
+	return nil
+}
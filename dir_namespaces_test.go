@@ -0,0 +1,87 @@
+package gobdb
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDirNamespaces(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	namespaces := NewDirNamespaces(dir)
+	names, err := namespaces.Namespaces()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(names) != 0 {
+		t.Error(names)
+	}
+
+	for i, name := range []string{"users", "orders"} {
+		bursts, snapshots, err := namespaces.Namespace(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dispatcher := NewDefaultBurstDispatcher(bursts)
+		database := NewDefaultDatabase(&testRoot{}, 0, dispatcher)
+		for j := 0; j <= i; j++ {
+			if _, err1, err2 := database.Write(&testWriter{10}); err1 != nil || err2 != nil {
+				t.Error(err1, err2)
+			}
+		}
+		if err := database.TakeSnapshot(testSnapshooter, snapshots); err != nil {
+			t.Error(err)
+		}
+		if err := dispatcher.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	names, err = namespaces.Namespaces()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(names) != 2 || names[0] != "orders" || names[1] != "users" {
+		t.Error(names)
+	}
+
+	bursts, snapshots, err := namespaces.Namespace("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	burstIds, err := bursts.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(burstIds) != 1 || burstIds[0].First() != 1 || burstIds[0].Last() != 2 {
+		t.Error(burstIds)
+	}
+	snapshotIds, err := snapshots.Snapshots()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(snapshotIds) != 1 || snapshotIds[0].Id() != 2 {
+		t.Error(snapshotIds)
+	}
+
+	if _, _, err := namespaces.Namespace("../escape"); err == nil {
+		t.Error(err)
+	}
+
+	if err := namespaces.RemoveNamespace("users"); err != nil {
+		t.Error(err)
+	}
+	names, err = namespaces.Namespaces()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(names) != 1 || names[0] != "orders" {
+		t.Error(names)
+	}
+}
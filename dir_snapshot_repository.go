@@ -5,60 +5,103 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"path/filepath"
 )
 
 const dirSnapshotRepositoryFileNameFormat = "snapshot-%d.gobdb"
 const dirSnapshotRepositoryFileNameScanFormat = dirSnapshotRepositoryFileNameFormat + "\n"
+const dirSnapshotRepositoryDeltaFileNameFormat = "snapshot-%d-%d.gobdb"
+const dirSnapshotRepositoryDeltaFileNameScanFormat = dirSnapshotRepositoryDeltaFileNameFormat + "\n"
 
-// A SnapshotRepository and WriteSnapshotRepository that uses one file per Snapshot.
+// The name of the file of a full Snapshot if the base is zero, or a delta one.
+func dirSnapshotRepositoryFileName(base, id TransactionId) string {
+	if base == 0 {
+		return fmt.Sprintf(dirSnapshotRepositoryFileNameFormat, id)
+	}
+	return fmt.Sprintf(dirSnapshotRepositoryDeltaFileNameFormat, base, id)
+}
+
+// A SnapshotRepository, WriteSnapshotRepository, DeltaSnapshotRepository,
+// WriteDeltaSnapshotRepository and LockRepository that uses one file per
+// Snapshot. The lock of the directory is held while a SnapshotWriter is open,
+// or from Lock() to Unlock(), so that only one DirSnapshotRepository writes to
+// it; reading does not.
 // Thread-safe, but SnapshotReaders and SnapshotWriters are not.
 type DirSnapshotRepository struct {
-	dir string
+	dir    string
+	fs     FileSystem
+	locker *dirLocker
 }
 
 func NewDirSnapshotRepository(dir string) *DirSnapshotRepository {
-	return &DirSnapshotRepository{dir}
+	return NewDirSnapshotRepositoryFS(dir, OSFileSystem{})
+}
+
+// New instance on the given FileSystem.
+func NewDirSnapshotRepositoryFS(dir string, fs FileSystem) *DirSnapshotRepository {
+	locker := &dirLocker{fs: fs, path: filepath.Join(dir, dirSnapshotRepositoryLockName)}
+	return &DirSnapshotRepository{dir, fs, locker}
+}
+
+// Implements LockRepository.Lock(). It returns an *ErrLocked if another
+// DirSnapshotRepository holds it, in this process or another one.
+func (r *DirSnapshotRepository) Lock() error {
+	return r.locker.lock()
+}
+
+// Implements LockRepository.Unlock().
+func (r *DirSnapshotRepository) Unlock() error {
+	return r.locker.unlock()
 }
 
 func (r *DirSnapshotRepository) Snapshots() ([]SnapshotId, error) {
-	file, err := os.Open(r.dir)
+	return r.snapshots(false)
+}
+
+func (r *DirSnapshotRepository) DeltaSnapshots() ([]SnapshotId, error) {
+	return r.snapshots(true)
+}
+
+func (r *DirSnapshotRepository) snapshots(delta bool) ([]SnapshotId, error) {
+	infos, err := r.fs.ReadDir(r.dir)
 	if err != nil {
 		return nil, err
 	}
-	infos, err1 := file.Readdir(-1)
-	err2 := file.Close()
-	if err1 != nil {
-		return nil, err1
-	}
-	if err2 != nil {
-		return nil, err2
-	}
 	ids := make([]SnapshotId, 0, len(infos))
 	for _, info := range infos {
 		name := info.Name()
-		var id int
-		if n, err := fmt.Sscanf(name, dirSnapshotRepositoryFileNameScanFormat, &id); n == 1 && err == nil {
-			ids = append(ids, &dirSnapshotId{TransactionId(id), r})
+		var base, id int
+		if delta {
+			if n, err := fmt.Sscanf(name, dirSnapshotRepositoryDeltaFileNameScanFormat, &base, &id); n == 2 && err == nil {
+				ids = append(ids, &dirSnapshotId{TransactionId(id), TransactionId(base), r})
+			}
+		} else if n, err := fmt.Sscanf(name, dirSnapshotRepositoryFileNameScanFormat, &id); n == 1 && err == nil {
+			ids = append(ids, &dirSnapshotId{TransactionId(id), 0, r})
 		}
 	}
 	return ids, nil
 }
 
 func (r *DirSnapshotRepository) WriteSnapshot(id TransactionId) (SnapshotWriter, error) {
-	file, err := ioutil.TempFile(r.dir, "tmp-snapshot-")
+	return r.WriteDeltaSnapshot(0, id)
+}
+
+func (r *DirSnapshotRepository) WriteDeltaSnapshot(base, id TransactionId) (SnapshotWriter, error) {
+	if err := r.locker.acquire(); err != nil {
+		return nil, err
+	}
+	file, err := r.fs.CreateTemp(r.dir, "tmp-snapshot-")
 	if err != nil {
+		r.locker.release()
 		return nil, err
 	}
 	writer := bufio.NewWriter(file)
 	encoder := gob.NewEncoder(writer)
-	return &dirSnapshotWriter{file, writer, encoder, id, r}, nil
+	return &dirSnapshotWriter{file, writer, encoder, id, base, r}, nil
 }
 
 type dirSnapshotId struct {
-	id         TransactionId
+	id, base   TransactionId
 	repository *DirSnapshotRepository
 }
 
@@ -66,23 +109,40 @@ func (id *dirSnapshotId) Id() TransactionId {
 	return id.id
 }
 
+func (id *dirSnapshotId) Base() TransactionId {
+	return id.base
+}
+
 func (id *dirSnapshotId) Repository() SnapshotRepository {
 	return id.repository
 }
 
+func (id *dirSnapshotId) path() string {
+	name := dirSnapshotRepositoryFileName(id.base, id.id)
+	return filepath.Join(id.repository.dir, name)
+}
+
+func (id *dirSnapshotId) Size() (int64, error) {
+	info, err := id.repository.fs.Stat(id.path())
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
 func (id *dirSnapshotId) Read() (SnapshotReader, error) {
-	name := fmt.Sprintf(dirSnapshotRepositoryFileNameFormat, id.id)
-	file, err := os.Open(filepath.Join(id.repository.dir, name))
+	file, err := id.repository.fs.Open(id.path())
 	if err != nil {
 		return nil, err
 	}
-	reader := bufio.NewReader(file)
-	decoder := gob.NewDecoder(reader)
-	return &dirSnapshotReader{file, decoder, id}, nil
+	counter := &countingReader{file, 0}
+	decoder := gob.NewDecoder(bufio.NewReader(counter))
+	return &dirSnapshotReader{file, counter, decoder, id}, nil
 }
 
 type dirSnapshotReader struct {
-	file    *os.File
+	file    File
+	counter *countingReader
 	decoder *gob.Decoder
 	mid     *dirSnapshotId
 }
@@ -94,18 +154,25 @@ func (br *dirSnapshotReader) Id() SnapshotId {
 func (br *dirSnapshotReader) Read() (Writer, error) {
 	var writer Writer
 	err := br.decoder.Decode(&writer)
+	if err == nil {
+		writer, err = migrateWriter(writer)
+	}
 	return writer, err
 }
 
+func (br *dirSnapshotReader) BytesRead() int64 {
+	return br.counter.count
+}
+
 func (br *dirSnapshotReader) Close() error {
 	return br.file.Close()
 }
 
 type dirSnapshotWriter struct {
-	file       *os.File
+	file       File
 	writer     *bufio.Writer
 	encoder    *gob.Encoder
-	id         TransactionId
+	id, base   TransactionId
 	repository *DirSnapshotRepository
 }
 
@@ -124,9 +191,22 @@ func (bw *dirSnapshotWriter) Close() error {
 	if bw.encoder == nil {
 		return errors.New("gobdb: close() on closed SnapshotWriter")
 	}
+	bw.encoder = nil
+	err := bw.close()
+	if err2 := bw.repository.locker.release(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// It writes the file and renames it.
+func (bw *dirSnapshotWriter) close() error {
 	oldname := bw.file.Name()
-	newname := fmt.Sprintf(dirSnapshotRepositoryFileNameFormat, bw.id)
+	newname := dirSnapshotRepositoryFileName(bw.base, bw.id)
 	err1 := bw.writer.Flush()
+	if err1 == nil {
+		err1 = bw.file.Sync()
+	}
 	err2 := bw.file.Close()
 	if err1 != nil {
 		return err1
@@ -134,5 +214,8 @@ func (bw *dirSnapshotWriter) Close() error {
 	if err2 != nil {
 		return err2
 	}
-	return os.Rename(oldname, filepath.Join(bw.repository.dir, newname))
+	if err := bw.repository.fs.Rename(oldname, filepath.Join(bw.repository.dir, newname)); err != nil {
+		return err
+	}
+	return bw.repository.fs.SyncDir(bw.repository.dir)
 }
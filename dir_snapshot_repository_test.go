@@ -50,6 +50,12 @@ func TestDirSnapshotRepositoryInterface(t *testing.T) {
 	if _, ok := i.(WriteSnapshotRepository); !ok {
 		t.Error(i)
 	}
+	if _, ok := i.(DeltaSnapshotRepository); !ok {
+		t.Error(i)
+	}
+	if _, ok := i.(WriteDeltaSnapshotRepository); !ok {
+		t.Error(i)
+	}
 }
 
 func TestDirSnapshotRepositoryEmpty(t *testing.T) {
@@ -129,7 +135,7 @@ func TestDirSnapshotRepositorySnapshots(t *testing.T) {
 		t.Error(err)
 	}
 	if rsnapshot.Id() != id {
-		t.Error(rsnapshot.Id)
+		t.Error(rsnapshot.Id())
 	}
 	defer rsnapshot.Close()
 
@@ -149,3 +155,55 @@ func TestDirSnapshotRepositorySnapshots(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestDirSnapshotRepositoryDelta(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repository := NewDirSnapshotRepository(dir)
+	for _, ids := range [][2]TransactionId{{0, 2}, {2, 3}, {3, 5}} {
+		wsnapshot, err := repository.WriteDeltaSnapshot(ids[0], ids[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := wsnapshot.Write(&testWriter{int(ids[1])}); err != nil {
+			t.Error(err)
+		}
+		if err := wsnapshot.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	snapshots, err := repository.Snapshots()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(snapshots) != 1 || snapshots[0].Id() != 2 {
+		t.Fatal(snapshots)
+	}
+	deltas, err := repository.DeltaSnapshots()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(deltas) != 2 {
+		t.Fatal(deltas)
+	}
+
+	chain := SnapshotChain(2, 5, deltas)
+	if len(chain) != 2 {
+		t.Fatal(chain)
+	}
+	root := &testRoot{}
+	for _, id := range append(snapshots, chain...) {
+		if err := ApplySnapshot(root, id); err != nil {
+			t.Error(err)
+		}
+	}
+	if root.counter != 10 {
+		t.Error(root.counter)
+	}
+}
@@ -0,0 +1,138 @@
+package gobdb
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The operations of the Dir repositories on the files of their directories.
+type FileSystem interface {
+	// It opens a file to read.
+	Open(name string) (File, error)
+	// It creates a new file to write in the directory, see ioutil.TempFile().
+	CreateTemp(dir, prefix string) (File, error)
+	Rename(oldname, newname string) error
+	// It creates a hard link to a file. It fails if the new name exists.
+	Link(oldname, newname string) error
+	Remove(name string) error
+	// It creates a directory and its parents, if they do not exist.
+	MkdirAll(dir string) error
+	// It removes a file or a directory with all its contents. It does nothing
+	// if it does not exist.
+	RemoveAll(name string) error
+	Stat(name string) (os.FileInfo, error)
+	// The files of a directory.
+	ReadDir(dir string) ([]os.FileInfo, error)
+	// It commits the creations, renames and removals of files in a directory
+	// to stable storage.
+	SyncDir(dir string) error
+	// It acquires an exclusive lock, released by the Closer. It returns an
+	// *ErrLocked if it is held by another one.
+	Lock(name string) (io.Closer, error)
+}
+
+// A file of a FileSystem.
+type File interface {
+	io.Reader
+	io.Writer
+	io.Seeker
+	io.Closer
+	Name() string
+	// It commits the data written to stable storage.
+	Sync() error
+}
+
+// The FileSystem of the operating system. The locks use flock, or LockFileEx
+// on Windows, and hold the pid of their holder. In other platforms they always
+// succeed.
+type OSFileSystem struct {
+}
+
+// Implements FileSystem.Open().
+func (OSFileSystem) Open(name string) (File, error) {
+	return os.Open(name)
+}
+
+// Implements FileSystem.CreateTemp().
+func (OSFileSystem) CreateTemp(dir, prefix string) (File, error) {
+	return ioutil.TempFile(dir, prefix)
+}
+
+// Implements FileSystem.Rename().
+func (OSFileSystem) Rename(oldname, newname string) error {
+	return os.Rename(oldname, newname)
+}
+
+// Implements FileSystem.Link().
+func (OSFileSystem) Link(oldname, newname string) error {
+	return os.Link(oldname, newname)
+}
+
+// Implements FileSystem.Remove().
+func (OSFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// Implements FileSystem.MkdirAll().
+func (OSFileSystem) MkdirAll(dir string) error {
+	return os.MkdirAll(dir, 0777)
+}
+
+// Implements FileSystem.RemoveAll().
+func (OSFileSystem) RemoveAll(name string) error {
+	return os.RemoveAll(name)
+}
+
+// Implements FileSystem.Stat().
+func (OSFileSystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// Implements FileSystem.ReadDir().
+func (OSFileSystem) ReadDir(dir string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(dir)
+}
+
+// Implements FileSystem.SyncDir().
+func (OSFileSystem) SyncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err1 := file.Sync()
+	err2 := file.Close()
+	if err1 != nil {
+		return err1
+	}
+	return err2
+}
+
+// Implements FileSystem.Lock().
+func (OSFileSystem) Lock(name string) (io.Closer, error) {
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if locked, err := lockFile(file); err != nil || !locked {
+		content, _ := ioutil.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		pid, _ := strconv.Atoi(strings.TrimSpace(string(content)))
+		return nil, &ErrLocked{name, pid}
+	}
+	if err := file.Truncate(0); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := fmt.Fprintln(file, os.Getpid()); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,153 @@
+package gobdbfault
+
+import (
+	"errors"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+// A BurstRepository that fails the list of Bursts (OpList), the opening of
+// BurstReaders (OpOpen), their reads (OpRead) and their closes (OpClose).
+// The BurstIds belong to it. Thread-safe if the other one is.
+type BurstRepository struct {
+	repository gobdb.BurstRepository
+	faults     *Faults
+}
+
+// New instance.
+func NewBurstRepository(repository gobdb.BurstRepository, faults *Faults) *BurstRepository {
+	return &BurstRepository{repository, faults}
+}
+
+// Implements BurstRepository.Bursts().
+func (r *BurstRepository) Bursts() ([]gobdb.BurstId, error) {
+	if err := r.faults.check(OpList); err != nil {
+		return nil, err
+	}
+	ids, err := r.repository.Bursts()
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range ids {
+		ids[i] = &burstId{id, r}
+	}
+	return ids, nil
+}
+
+type burstId struct {
+	gobdb.BurstId
+	repository *BurstRepository
+}
+
+func (id *burstId) Repository() gobdb.BurstRepository {
+	return id.repository
+}
+
+func (id *burstId) Read() (gobdb.BurstReader, error) {
+	if err := id.repository.faults.check(OpOpen); err != nil {
+		return nil, err
+	}
+	reader, err := id.BurstId.Read()
+	if err != nil {
+		return nil, err
+	}
+	return &burstReader{reader, id}, nil
+}
+
+type burstReader struct {
+	gobdb.BurstReader
+	id *burstId
+}
+
+func (br *burstReader) Id() gobdb.BurstId {
+	return br.id
+}
+
+func (br *burstReader) Read() (gobdb.Transaction, error) {
+	if err := br.id.repository.faults.check(OpRead); err != nil {
+		return gobdb.Transaction{}, err
+	}
+	return br.BurstReader.Read()
+}
+
+func (br *burstReader) Close() error {
+	err := br.BurstReader.Close()
+	if err := br.id.repository.faults.check(OpClose); err != nil {
+		return err
+	}
+	return err
+}
+
+// A WriteBurstRepository that fails the creation of BurstWriters (OpCreate),
+// their writes (OpWrite), their syncs (OpSync) if they implement gobdb.Syncer
+// and their closes (OpClose). A failed close discards the Burst. Thread-safe if the other one is.
+type WriteBurstRepository struct {
+	repository gobdb.WriteBurstRepository
+	faults     *Faults
+}
+
+// New instance.
+func NewWriteBurstRepository(repository gobdb.WriteBurstRepository, faults *Faults) *WriteBurstRepository {
+	return &WriteBurstRepository{repository, faults}
+}
+
+// Implements WriteBurstRepository.WriteBurst().
+func (r *WriteBurstRepository) WriteBurst() (gobdb.BurstWriter, error) {
+	if err := r.faults.check(OpCreate); err != nil {
+		return nil, err
+	}
+	writer, err := r.repository.WriteBurst()
+	if err != nil {
+		return nil, err
+	}
+	bw := &burstWriter{writer, r.faults, false}
+	if _, ok := writer.(gobdb.Syncer); ok {
+		return &syncBurstWriter{bw}, nil
+	}
+	return bw, nil
+}
+
+// Implements gobdb.HighWaterMarker.HighWaterMark(). It is zero if the other
+// WriteBurstRepository does not implement it.
+func (r *WriteBurstRepository) HighWaterMark() (gobdb.TransactionId, error) {
+	if marker, ok := r.repository.(gobdb.HighWaterMarker); ok {
+		return marker.HighWaterMark()
+	}
+	return 0, nil
+}
+
+type burstWriter struct {
+	gobdb.BurstWriter
+	faults *Faults
+	closed bool
+}
+
+func (bw *burstWriter) Write(transaction gobdb.Transaction) error {
+	if err := bw.faults.check(OpWrite); err != nil {
+		return err
+	}
+	return bw.BurstWriter.Write(transaction)
+}
+
+func (bw *burstWriter) Close() error {
+	if bw.closed {
+		return errors.New("gobdb: close() on closed BurstWriter")
+	}
+	bw.closed = true
+	if err := bw.faults.check(OpClose); err != nil {
+		return err
+	}
+	return bw.BurstWriter.Close()
+}
+
+// A burstWriter of a BurstWriter that implements gobdb.Syncer.
+type syncBurstWriter struct {
+	*burstWriter
+}
+
+func (bw *syncBurstWriter) Sync() error {
+	if err := bw.faults.check(OpSync); err != nil {
+		return err
+	}
+	return bw.BurstWriter.(gobdb.Syncer).Sync()
+}
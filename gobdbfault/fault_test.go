@@ -0,0 +1,170 @@
+package gobdbfault
+
+import (
+	"testing"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+type testRoot struct {
+	counter int
+}
+
+type testWriter struct {
+	Increment int
+}
+
+func (op *testWriter) Write(root gobdb.Root) (interface{}, error) {
+	r := root.(*testRoot)
+	r.counter += op.Increment
+	return r.counter, nil
+}
+
+func testSnapshooter(root gobdb.Root, write func(...gobdb.Writer) error) error {
+	return write(&testWriter{root.(*testRoot).counter})
+}
+
+func init() {
+	gobdb.RegisterWriter(&testWriter{})
+}
+
+func testWriteBurst(t *testing.T, repository gobdb.WriteBurstRepository, ids ...gobdb.TransactionId) {
+	wburst, err := repository.WriteBurst()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range ids {
+		if err := wburst.Write(gobdb.Transaction{Id: id, Writer: &testWriter{int(id)}}); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := wburst.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInterfaces(t *testing.T) {
+
+	for _, i := range []interface{}{NewBurstRepository(nil, nil), NewSnapshotRepository(nil, nil)} {
+		_, ok1 := i.(gobdb.BurstRepository)
+		_, ok2 := i.(gobdb.SnapshotRepository)
+		if !ok1 && !ok2 {
+			t.Error(i)
+		}
+	}
+	if _, ok := interface{}(NewWriteBurstRepository(nil, nil)).(gobdb.WriteBurstRepository); !ok {
+		t.Error("WriteBurstRepository")
+	}
+	if _, ok := interface{}(NewWriteSnapshotRepository(nil, nil)).(gobdb.WriteSnapshotRepository); !ok {
+		t.Error("WriteSnapshotRepository")
+	}
+	if _, ok := interface{}(NewFileSystem(nil)).(gobdb.FileSystem); !ok {
+		t.Error("FileSystem")
+	}
+}
+
+func TestFaults(t *testing.T) {
+
+	faults := NewFaults()
+	faults.FailAt(OpWrite, 2)
+	faults.FailFrom(OpClose, 3)
+	for n, expected := range []bool{false, true, false} {
+		if err := faults.check(OpWrite); (err != nil) != expected {
+			t.Error(n, err)
+		}
+	}
+	for n, expected := range []bool{false, false, true, true} {
+		if err := faults.check(OpClose); (err != nil) != expected {
+			t.Error(n, err)
+		} else if e, ok := err.(*ErrInjected); expected && (!ok || e.Op != OpClose || e.N != n+1) {
+			t.Error(n, err)
+		}
+	}
+	if faults.Count(OpWrite) != 3 || faults.Count(OpRead) != 0 {
+		t.Error(faults.Count(OpWrite), faults.Count(OpRead))
+	}
+}
+
+func TestWriteBurstRepository(t *testing.T) {
+
+	faults := NewFaults()
+	faults.FailAt(OpWrite, 3)
+	repository := gobdb.NewMemBurstRepository()
+	dispatcher := gobdb.NewDefaultBurstDispatcher(NewWriteBurstRepository(repository, faults))
+	database := gobdb.NewDefaultDatabase(&testRoot{}, 0, dispatcher)
+	for i := 1; i <= 3; i++ {
+		_, err1, err2 := database.Write(&testWriter{i})
+		if err1 != nil || (err2 != nil) != (i == 3) {
+			t.Error(i, err1, err2)
+		}
+	}
+	if err := database.Latched(); err == nil {
+		t.Error(err)
+	}
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+	if mark, err := repository.HighWaterMark(); err != nil || mark != 2 {
+		t.Error(mark, err)
+	}
+}
+
+func TestBurstRepository(t *testing.T) {
+
+	faults := NewFaults()
+	faults.FailAt(OpRead, 3)
+	repository := gobdb.NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 2, 3)
+
+	bursts, err := NewBurstRepository(repository, faults).Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	root := &testRoot{}
+	var id gobdb.TransactionId
+	if err := gobdb.ApplyBursts(root, 0, &id, bursts); err == nil {
+		t.Error(err)
+	}
+	if id != 2 || root.counter != 3 {
+		t.Error(id, root.counter)
+	}
+
+	faults.FailAt(OpList, 2)
+	if _, err := NewBurstRepository(repository, faults).Bursts(); err == nil {
+		t.Error(err)
+	}
+}
+
+func TestSnapshotRepositories(t *testing.T) {
+
+	faults := NewFaults()
+	faults.FailAt(OpClose, 1)
+	repository := gobdb.NewMemSnapshotRepository()
+	wrepository := NewWriteSnapshotRepository(repository, faults)
+	database := gobdb.NewDefaultDatabase(&testRoot{5}, 1, nil)
+	if err := database.TakeSnapshot(testSnapshooter, wrepository); err == nil {
+		t.Error(err)
+	}
+	if snapshots, err := repository.Snapshots(); err != nil || len(snapshots) != 0 {
+		t.Error(snapshots, err)
+	}
+	if err := database.TakeSnapshot(testSnapshooter, wrepository); err != nil {
+		t.Error(err)
+	}
+
+	faults.FailAt(OpOpen, 1)
+	snapshots, err := NewSnapshotRepository(repository, faults).Snapshots()
+	if err != nil || len(snapshots) != 1 {
+		t.Fatal(snapshots, err)
+	}
+	if err := gobdb.ApplySnapshot(&testRoot{}, snapshots[0]); err == nil {
+		t.Error(err)
+	}
+	root := &testRoot{}
+	if err := gobdb.ApplySnapshot(root, snapshots[0]); err != nil {
+		t.Error(err)
+	}
+	if root.counter != 5 {
+		t.Error(root.counter)
+	}
+}
@@ -0,0 +1,102 @@
+// Package gobdbfault implements gobdb repositories and a gobdb FileSystem that
+// fail on demand, to test the handling of crashes and I/O errors.
+package gobdbfault
+
+import (
+	"fmt"
+	"sync"
+)
+
+// A kind of operation that may fail.
+type Op string
+
+const (
+	OpList    Op = "list"
+	OpCreate  Op = "create"
+	OpOpen    Op = "open"
+	OpRead    Op = "read"
+	OpWrite   Op = "write"
+	OpSync    Op = "sync"
+	OpSyncDir Op = "syncdir"
+	OpClose   Op = "close"
+	OpRename  Op = "rename"
+	OpLink    Op = "link"
+	OpRemove  Op = "remove"
+	OpMkdir   Op = "mkdir"
+	OpLock    Op = "lock"
+)
+
+// The error of an operation that has been made to fail.
+type ErrInjected struct {
+	Op Op
+	// The number of the call of the operation, from 1.
+	N int
+}
+
+func (e *ErrInjected) Error() string {
+	return fmt.Sprintf("gobdb: injected failure of %s #%d", e.Op, e.N)
+}
+
+// It counts the calls of every operation and decides which ones fail. The zero
+// value fails nothing. Thread-safe.
+type Faults struct {
+	mutex  sync.Mutex
+	counts map[Op]int
+	at     map[Op]map[int]bool
+	from   map[Op]int
+}
+
+// New instance.
+func NewFaults() *Faults {
+	return &Faults{}
+}
+
+// It makes the nth call of the operation fail, counting from 1.
+func (f *Faults) FailAt(op Op, n int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.at == nil {
+		f.at = make(map[Op]map[int]bool)
+	}
+	if f.at[op] == nil {
+		f.at[op] = make(map[int]bool)
+	}
+	f.at[op][n] = true
+}
+
+// It makes every call of the operation fail from the nth one, like a full
+// disk. Zero disables it.
+func (f *Faults) FailFrom(op Op, n int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.from == nil {
+		f.from = make(map[Op]int)
+	}
+	f.from[op] = n
+}
+
+// The number of calls of the operation.
+func (f *Faults) Count(op Op) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.counts[op]
+}
+
+// It counts a call of the operation and returns an *ErrInjected if it must
+// fail.
+func (f *Faults) check(op Op) error {
+	if f == nil {
+		return nil
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.counts == nil {
+		f.counts = make(map[Op]int)
+	}
+	f.counts[op]++
+	n := f.counts[op]
+	if f.at[op][n] || f.from[op] > 0 && n >= f.from[op] {
+		return &ErrInjected{op, n}
+	}
+	return nil
+}
@@ -0,0 +1,407 @@
+package gobdbfault
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+// A gobdb FileSystem in memory that fails the operations of the Faults. The
+// data written to a File is lost on Crash() unless it is synced, and so are
+// the creations, renames, links and removals of files unless their directory
+// is synced. The directories and RemoveAll() are durable. Thread-safe, but
+// the Files are not.
+type FileSystem struct {
+	mutex  sync.Mutex
+	faults *Faults
+	files  map[string]*memFile
+	// the files as of the last SyncDir() of their directories
+	durable map[string]*memFile
+	dirs    map[string]bool
+	locks   map[string]bool
+	count   int
+}
+
+type memFile struct {
+	data, synced []byte
+}
+
+// New instance. The Faults are optional.
+func NewFileSystem(faults *Faults) *FileSystem {
+	return &FileSystem{faults: faults, files: make(map[string]*memFile), durable: make(map[string]*memFile), dirs: make(map[string]bool), locks: make(map[string]bool)}
+}
+
+// The names of all files, sorted.
+func (fs *FileSystem) Names() []string {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	names := make([]string, 0, len(fs.files))
+	for name := range fs.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// It truncates or extends with zeros a file, including its synced data.
+func (fs *FileSystem) Truncate(name string, size int64) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	file, ok := fs.files[name]
+	if !ok {
+		return &os.PathError{Op: "truncate", Path: name, Err: os.ErrNotExist}
+	}
+	file.data = resize(file.data, size)
+	file.synced = resize(file.synced, size)
+	return nil
+}
+
+func resize(data []byte, size int64) []byte {
+	if int64(len(data)) >= size {
+		return data[:size]
+	}
+	return append(data, make([]byte, size-int64(len(data)))...)
+}
+
+// It simulates a crash of the process and the machine: the files of every
+// directory are reverted to the ones synced, their data is reverted to the
+// one synced, and the locks are released. The open Files must not be used any
+// more.
+func (fs *FileSystem) Crash() {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	fs.files = make(map[string]*memFile, len(fs.durable))
+	for name, file := range fs.durable {
+		fs.files[name] = file
+		file.data = append([]byte(nil), file.synced...)
+	}
+	fs.locks = make(map[string]bool)
+}
+
+// Implements FileSystem.Open(). It fails with OpOpen.
+func (fs *FileSystem) Open(name string) (gobdb.File, error) {
+	if err := fs.faults.check(OpOpen); err != nil {
+		return nil, err
+	}
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	file, ok := fs.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return &memHandle{fs, name, file, 0, false}, nil
+}
+
+// Implements FileSystem.CreateTemp(). It fails with OpCreate.
+func (fs *FileSystem) CreateTemp(dir, prefix string) (gobdb.File, error) {
+	if err := fs.faults.check(OpCreate); err != nil {
+		return nil, err
+	}
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	fs.count++
+	name := filepath.Join(dir, fmt.Sprintf("%s%d", prefix, fs.count))
+	file := &memFile{}
+	fs.files[name] = file
+	return &memHandle{fs, name, file, 0, false}, nil
+}
+
+// Implements FileSystem.Rename(). It fails with OpRename.
+func (fs *FileSystem) Rename(oldname, newname string) error {
+	if err := fs.faults.check(OpRename); err != nil {
+		return err
+	}
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	file, ok := fs.files[oldname]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: os.ErrNotExist}
+	}
+	delete(fs.files, oldname)
+	fs.files[newname] = file
+	return nil
+}
+
+// Implements FileSystem.Link(). It fails with OpLink. Both names share the
+// data of the file.
+func (fs *FileSystem) Link(oldname, newname string) error {
+	if err := fs.faults.check(OpLink); err != nil {
+		return err
+	}
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	file, ok := fs.files[oldname]
+	if !ok {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: os.ErrNotExist}
+	}
+	if _, ok := fs.files[newname]; ok {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: os.ErrExist}
+	}
+	fs.files[newname] = file
+	return nil
+}
+
+// Implements FileSystem.Remove(). It fails with OpRemove.
+func (fs *FileSystem) Remove(name string) error {
+	if err := fs.faults.check(OpRemove); err != nil {
+		return err
+	}
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	if _, ok := fs.files[name]; !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	delete(fs.files, name)
+	return nil
+}
+
+// Implements FileSystem.MkdirAll(). It fails with OpMkdir. The directories
+// of the files exist even if they have not been created.
+func (fs *FileSystem) MkdirAll(dir string) error {
+	if err := fs.faults.check(OpMkdir); err != nil {
+		return err
+	}
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	fs.dirs[filepath.Clean(dir)] = true
+	return nil
+}
+
+// Implements FileSystem.RemoveAll(). It fails with OpRemove.
+func (fs *FileSystem) RemoveAll(name string) error {
+	if err := fs.faults.check(OpRemove); err != nil {
+		return err
+	}
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	name = filepath.Clean(name)
+	for _, files := range []map[string]*memFile{fs.files, fs.durable} {
+		for file := range files {
+			if file == name || strings.HasPrefix(file, name+string(filepath.Separator)) {
+				delete(files, file)
+			}
+		}
+	}
+	for dir := range fs.dirs {
+		if dir == name || strings.HasPrefix(dir, name+string(filepath.Separator)) {
+			delete(fs.dirs, dir)
+		}
+	}
+	return nil
+}
+
+// Implements FileSystem.Stat().
+func (fs *FileSystem) Stat(name string) (os.FileInfo, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	file, ok := fs.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return &memFileInfo{filepath.Base(name), int64(len(file.data)), false}, nil
+}
+
+// Implements FileSystem.ReadDir(). It fails with OpList.
+func (fs *FileSystem) ReadDir(dir string) ([]os.FileInfo, error) {
+	if err := fs.faults.check(OpList); err != nil {
+		return nil, err
+	}
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	dir = filepath.Clean(dir)
+	infos := []os.FileInfo{}
+	subdirs := map[string]bool{}
+	for name, file := range fs.files {
+		if filepath.Dir(name) == dir {
+			infos = append(infos, &memFileInfo{filepath.Base(name), int64(len(file.data)), false})
+		} else if subdir, ok := childDir(dir, filepath.Dir(name)); ok {
+			subdirs[subdir] = true
+		}
+	}
+	for name := range fs.dirs {
+		if subdir, ok := childDir(dir, name); ok {
+			subdirs[subdir] = true
+		}
+	}
+	for subdir := range subdirs {
+		infos = append(infos, &memFileInfo{subdir, 0, true})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+	return infos, nil
+}
+
+// The name of the child of the directory that contains the other one, if it
+// is inside it.
+func childDir(dir, other string) (string, bool) {
+	rel, err := filepath.Rel(dir, other)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return strings.SplitN(rel, string(filepath.Separator), 2)[0], true
+}
+
+// Implements FileSystem.SyncDir(). It fails with OpSyncDir. It makes the
+// creations, renames, links and removals of files in the directory durable.
+func (fs *FileSystem) SyncDir(dir string) error {
+	if err := fs.faults.check(OpSyncDir); err != nil {
+		return err
+	}
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	dir = filepath.Clean(dir)
+	for name := range fs.durable {
+		if _, ok := fs.files[name]; !ok && filepath.Dir(name) == dir {
+			delete(fs.durable, name)
+		}
+	}
+	for name, file := range fs.files {
+		if filepath.Dir(name) == dir {
+			fs.durable[name] = file
+		}
+	}
+	return nil
+}
+
+// Implements FileSystem.Lock(). It fails with OpLock.
+func (fs *FileSystem) Lock(name string) (io.Closer, error) {
+	if err := fs.faults.check(OpLock); err != nil {
+		return nil, err
+	}
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	if fs.locks[name] {
+		return nil, &gobdb.ErrLocked{Path: name, Pid: os.Getpid()}
+	}
+	fs.locks[name] = true
+	return &memLock{fs, name}, nil
+}
+
+type memLock struct {
+	fs   *FileSystem
+	name string
+}
+
+func (l *memLock) Close() error {
+	l.fs.mutex.Lock()
+	defer l.fs.mutex.Unlock()
+	delete(l.fs.locks, l.name)
+	return nil
+}
+
+// An open file. It fails the reads with OpRead, the writes with OpWrite, the
+// syncs with OpSync and the closes with OpClose.
+type memHandle struct {
+	fs     *FileSystem
+	name   string
+	file   *memFile
+	offset int64
+	closed bool
+}
+
+func (h *memHandle) Name() string {
+	return h.name
+}
+
+func (h *memHandle) Read(p []byte) (int, error) {
+	if err := h.fs.faults.check(OpRead); err != nil {
+		return 0, err
+	}
+	h.fs.mutex.Lock()
+	defer h.fs.mutex.Unlock()
+	if h.offset >= int64(len(h.file.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, h.file.data[h.offset:])
+	h.offset += int64(n)
+	return n, nil
+}
+
+func (h *memHandle) Write(p []byte) (int, error) {
+	if err := h.fs.faults.check(OpWrite); err != nil {
+		return 0, err
+	}
+	h.fs.mutex.Lock()
+	defer h.fs.mutex.Unlock()
+	if end := h.offset + int64(len(p)); end > int64(len(h.file.data)) {
+		h.file.data = resize(h.file.data, end)
+	}
+	n := copy(h.file.data[h.offset:], p)
+	h.offset += int64(n)
+	return n, nil
+}
+
+func (h *memHandle) Seek(offset int64, whence int) (int64, error) {
+	h.fs.mutex.Lock()
+	defer h.fs.mutex.Unlock()
+	switch whence {
+	case io.SeekCurrent:
+		offset += h.offset
+	case io.SeekEnd:
+		offset += int64(len(h.file.data))
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("gobdb: negative offset in %s", h.name)
+	}
+	h.offset = offset
+	return offset, nil
+}
+
+func (h *memHandle) Sync() error {
+	if err := h.fs.faults.check(OpSync); err != nil {
+		return err
+	}
+	h.fs.mutex.Lock()
+	defer h.fs.mutex.Unlock()
+	h.file.synced = append([]byte(nil), h.file.data...)
+	return nil
+}
+
+func (h *memHandle) Close() error {
+	if h.closed {
+		return fmt.Errorf("gobdb: close of closed %s", h.name)
+	}
+	h.closed = true
+	return h.fs.faults.check(OpClose)
+}
+
+type memFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i *memFileInfo) Name() string {
+	return i.name
+}
+
+func (i *memFileInfo) Size() int64 {
+	return i.size
+}
+
+func (i *memFileInfo) Mode() os.FileMode {
+	if i.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+func (i *memFileInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+func (i *memFileInfo) IsDir() bool {
+	return i.dir
+}
+
+func (i *memFileInfo) Sys() interface{} {
+	return nil
+}
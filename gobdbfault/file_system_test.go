@@ -0,0 +1,281 @@
+package gobdbfault
+
+import (
+	"context"
+	"testing"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+func TestFileSystemCrash(t *testing.T) {
+
+	fs := NewFileSystem(nil)
+	repository := gobdb.NewDirBurstRepositoryFS("/db", fs)
+	testWriteBurst(t, repository, 1, 2)
+
+	wburst, err := repository.WriteBurst()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wburst.Write(gobdb.Transaction{Id: 3, Writer: &testWriter{3}}); err != nil {
+		t.Error(err)
+	}
+	fs.Crash()
+
+	// the temporary file of the open Burst was not in a synced directory
+	if names := fs.Names(); len(names) != 1 || names[0] != "/db/burst-1-2.gobdb" {
+		t.Error(names)
+	}
+	bursts, err := gobdb.NewDirBurstRepositoryFS("/db", fs).Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	root := &testRoot{}
+	var id gobdb.TransactionId
+	if err := gobdb.ApplyBursts(root, 0, &id, bursts); err != nil {
+		t.Error(err)
+	}
+	if id != 2 || root.counter != 3 {
+		t.Error(id, root.counter)
+	}
+
+	// the lock has been released
+	if err := gobdb.NewDirBurstRepositoryFS("/db", fs).Lock(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFileSystemCrashWithoutSyncDir(t *testing.T) {
+
+	faults := NewFaults()
+	faults.FailAt(OpSyncDir, 2)
+	fs := NewFileSystem(faults)
+	repository := gobdb.NewDirBurstRepositoryFS("/db", fs)
+	testWriteBurst(t, repository, 1)
+
+	wburst, err := repository.WriteBurst()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wburst.Write(gobdb.Transaction{Id: 2, Writer: &testWriter{2}}); err != nil {
+		t.Error(err)
+	}
+	if err := wburst.Close(); err == nil {
+		t.Error(err)
+	}
+	if names := fs.Names(); len(names) != 2 {
+		t.Error(names)
+	}
+	fs.Crash()
+
+	if names := fs.Names(); len(names) != 1 || names[0] != "/db/burst-1-1.gobdb" {
+		t.Error(names)
+	}
+}
+
+func TestFileSystemCrashWriteAhead(t *testing.T) {
+
+	fs, faults := NewFileSystem(nil), NewFaults()
+	faults.FailAt(OpSync, 3)
+	repository := gobdb.NewDirBurstRepositoryFS("/db", fs)
+	dispatcher := gobdb.NewDefaultBurstDispatcher(NewWriteBurstRepository(repository, faults))
+	database := gobdb.NewDefaultDatabase(&testRoot{}, 0, dispatcher)
+	database.SetWriteAhead(true)
+	for i := 1; i <= 3; i++ {
+		_, err1, err2 := database.Write(&testWriter{i})
+		if err1 != nil || (err2 != nil) != (i == 3) {
+			t.Error(i, err1, err2)
+		}
+	}
+	fs.Crash()
+
+	// the synced Transactions are in one unclosed Burst
+	bursts, err := gobdb.NewDirBurstRepositoryFS("/db", fs).Bursts()
+	if err != nil || len(bursts) != 1 || bursts[0].First() != 1 || bursts[0].Last() != 2 {
+		t.Fatal(bursts, err)
+	}
+	root := &testRoot{}
+	var id gobdb.TransactionId
+	if err := gobdb.ApplyBursts(root, 0, &id, bursts); err != nil || id != 2 || root.counter != 3 {
+		t.Error(id, root.counter, err)
+	}
+	if manifest, err := gobdb.Backup(nil, gobdb.NewDirBurstRepositoryFS("/db", fs), nil, "/backup"); err != nil || manifest.LastId != 2 {
+		t.Error(manifest, err)
+	}
+	if manifest, err := gobdb.RestoreFS(fs, "/backup", "/restored", "/restored"); err != nil || manifest.LastId != 2 {
+		t.Error(manifest, err)
+	}
+
+	testWriteBurst(t, gobdb.NewDirBurstRepositoryFS("/db", fs), 3)
+	bursts, err = gobdb.NewDirBurstRepositoryFS("/db", fs).Bursts()
+	if err != nil || len(bursts) != 2 {
+		t.Fatal(bursts, err)
+	}
+	root, id = &testRoot{}, 0
+	if err := gobdb.ApplyBursts(root, 0, &id, bursts); err != nil || id != 3 || root.counter != 6 {
+		t.Error(id, root.counter, err)
+	}
+}
+
+func TestFileSystemTruncate(t *testing.T) {
+
+	fs := NewFileSystem(nil)
+	repository := gobdb.NewDirBurstRepositoryFS("/db", fs)
+	testWriteBurst(t, repository, 1, 2, 3)
+
+	bursts, err := repository.Bursts()
+	if err != nil || len(bursts) != 1 {
+		t.Fatal(bursts, err)
+	}
+	size, err := bursts[0].(gobdb.Sizer).Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Truncate("/db/burst-1-3.gobdb", size-2); err != nil {
+		t.Error(err)
+	}
+	root := &testRoot{}
+	var id gobdb.TransactionId
+	if err := gobdb.ApplyBursts(root, 0, &id, bursts); err == nil {
+		t.Error(err)
+	}
+	if id != 2 || root.counter != 3 {
+		t.Error(id, root.counter)
+	}
+}
+
+func TestFileSystemFaults(t *testing.T) {
+
+	faults := NewFaults()
+	faults.FailAt(OpRename, 1)
+	faults.FailAt(OpSync, 2)
+	fs := NewFileSystem(faults)
+	repository := gobdb.NewDirBurstRepositoryFS("/db", fs)
+
+	for i := gobdb.TransactionId(1); i <= 3; i++ {
+		wburst, err := repository.WriteBurst()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := wburst.Write(gobdb.Transaction{Id: i, Writer: &testWriter{int(i)}}); err != nil {
+			t.Error(err)
+		}
+		if err := wburst.Close(); (err != nil) != (i < 3) {
+			t.Error(i, err)
+		}
+	}
+	bursts, err := repository.Bursts()
+	if err != nil || len(bursts) != 1 || bursts[0].First() != 3 {
+		t.Error(bursts, err)
+	}
+	if faults.Count(OpCreate) != 3 || faults.Count(OpRename) != 2 {
+		t.Error(faults.Count(OpCreate), faults.Count(OpRename))
+	}
+}
+
+func TestFileSystemSyncDirFault(t *testing.T) {
+
+	faults := NewFaults()
+	faults.FailAt(OpSyncDir, 1)
+	fs := NewFileSystem(faults)
+	repository := gobdb.NewDirSnapshotRepositoryFS("/db", fs)
+
+	wsnapshot, err := repository.WriteSnapshot(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wsnapshot.Write(&testWriter{1}); err != nil {
+		t.Error(err)
+	}
+	if err := wsnapshot.Close(); err == nil {
+		t.Error(err)
+	}
+	if faults.Count(OpSyncDir) != 1 {
+		t.Error(faults.Count(OpSyncDir))
+	}
+}
+
+func TestFileSystemNamespaces(t *testing.T) {
+
+	faults := NewFaults()
+	faults.FailAt(OpMkdir, 2)
+	faults.FailAt(OpRemove, 1)
+	fs := NewFileSystem(faults)
+	namespaces := gobdb.NewDirNamespacesFS("/db", fs)
+
+	bursts, _, err := namespaces.Namespace("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := namespaces.Namespace("orders"); err == nil {
+		t.Error(err)
+	}
+	testWriteBurst(t, bursts, 1)
+	if _, _, err := namespaces.Namespace("empty"); err != nil {
+		t.Error(err)
+	}
+	if names, err := namespaces.Namespaces(); err != nil || len(names) != 2 || names[0] != "empty" || names[1] != "users" {
+		t.Error(names, err)
+	}
+
+	if err := namespaces.RemoveNamespace("users"); err == nil {
+		t.Error(err)
+	}
+	if err := namespaces.RemoveNamespace("users"); err != nil {
+		t.Error(err)
+	}
+	if names, err := namespaces.Namespaces(); err != nil || len(names) != 1 || names[0] != "empty" {
+		t.Error(names, err)
+	}
+	if names := fs.Names(); len(names) != 0 {
+		t.Error(names)
+	}
+	if faults.Count(OpSyncDir) < 4 {
+		t.Error(faults.Count(OpSyncDir))
+	}
+}
+
+func TestFileSystemBackup(t *testing.T) {
+
+	faults := NewFaults()
+	fs := NewFileSystem(faults)
+	bursts := gobdb.NewDirBurstRepositoryFS("/db", fs)
+	snapshots := gobdb.NewDirSnapshotRepositoryFS("/db", fs)
+	testWriteBurst(t, bursts, 1, 2)
+	database := gobdb.NewDefaultDatabase(&testRoot{3}, 2, nil)
+	if err := database.TakeSnapshot(testSnapshooter, snapshots); err != nil {
+		t.Error(err)
+	}
+	testWriteBurst(t, bursts, 3)
+
+	manifest, err := gobdb.Backup(nil, bursts, snapshots, "/backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.LastId != 3 || len(manifest.Snapshots) != 1 || len(manifest.Bursts) != 1 {
+		t.Error(manifest)
+	}
+	if faults.Count(OpLink) != 2 {
+		t.Error(faults.Count(OpLink))
+	}
+
+	faults.FailAt(OpList, faults.Count(OpList)+1)
+	if _, err := gobdb.RestoreFS(fs, "/backup", "/restored", "/restored"); err == nil {
+		t.Error(err)
+	} else if _, ok := err.(*ErrInjected); !ok {
+		t.Error(err)
+	}
+
+	faults.FailFrom(OpLink, 1)
+	if _, err := gobdb.RestoreFS(fs, "/backup", "/restored", "/restored"); err != nil {
+		t.Fatal(err)
+	}
+	root := &testRoot{}
+	var id gobdb.TransactionId
+	if _, err := gobdb.Recover(context.Background(), root, gobdb.NewDirSnapshotRepositoryFS("/restored", fs), gobdb.NewDirBurstRepositoryFS("/restored", fs), &id, nil); err != nil {
+		t.Error(err)
+	}
+	if id != 3 || root.counter != 6 {
+		t.Error(id, root.counter)
+	}
+}
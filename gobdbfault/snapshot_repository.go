@@ -0,0 +1,128 @@
+package gobdbfault
+
+import (
+	"errors"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+// A SnapshotRepository that fails the list of Snapshots (OpList), the opening
+// of SnapshotReaders (OpOpen), their reads (OpRead) and their closes
+// (OpClose). The SnapshotIds belong to it. Thread-safe if the other one is.
+type SnapshotRepository struct {
+	repository gobdb.SnapshotRepository
+	faults     *Faults
+}
+
+// New instance.
+func NewSnapshotRepository(repository gobdb.SnapshotRepository, faults *Faults) *SnapshotRepository {
+	return &SnapshotRepository{repository, faults}
+}
+
+// Implements SnapshotRepository.Snapshots().
+func (r *SnapshotRepository) Snapshots() ([]gobdb.SnapshotId, error) {
+	if err := r.faults.check(OpList); err != nil {
+		return nil, err
+	}
+	ids, err := r.repository.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range ids {
+		ids[i] = &snapshotId{id, r}
+	}
+	return ids, nil
+}
+
+type snapshotId struct {
+	gobdb.SnapshotId
+	repository *SnapshotRepository
+}
+
+func (id *snapshotId) Repository() gobdb.SnapshotRepository {
+	return id.repository
+}
+
+func (id *snapshotId) Read() (gobdb.SnapshotReader, error) {
+	if err := id.repository.faults.check(OpOpen); err != nil {
+		return nil, err
+	}
+	reader, err := id.SnapshotId.Read()
+	if err != nil {
+		return nil, err
+	}
+	return &snapshotReader{reader, id}, nil
+}
+
+type snapshotReader struct {
+	gobdb.SnapshotReader
+	id *snapshotId
+}
+
+func (sr *snapshotReader) Id() gobdb.SnapshotId {
+	return sr.id
+}
+
+func (sr *snapshotReader) Read() (gobdb.Writer, error) {
+	if err := sr.id.repository.faults.check(OpRead); err != nil {
+		return nil, err
+	}
+	return sr.SnapshotReader.Read()
+}
+
+func (sr *snapshotReader) Close() error {
+	err := sr.SnapshotReader.Close()
+	if err := sr.id.repository.faults.check(OpClose); err != nil {
+		return err
+	}
+	return err
+}
+
+// A WriteSnapshotRepository that fails the creation of SnapshotWriters
+// (OpCreate), their writes (OpWrite) and their closes (OpClose). A failed close
+// discards the Snapshot. Thread-safe if the other one is.
+type WriteSnapshotRepository struct {
+	repository gobdb.WriteSnapshotRepository
+	faults     *Faults
+}
+
+// New instance.
+func NewWriteSnapshotRepository(repository gobdb.WriteSnapshotRepository, faults *Faults) *WriteSnapshotRepository {
+	return &WriteSnapshotRepository{repository, faults}
+}
+
+// Implements WriteSnapshotRepository.WriteSnapshot().
+func (r *WriteSnapshotRepository) WriteSnapshot(id gobdb.TransactionId) (gobdb.SnapshotWriter, error) {
+	if err := r.faults.check(OpCreate); err != nil {
+		return nil, err
+	}
+	writer, err := r.repository.WriteSnapshot(id)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshotWriter{writer, r.faults, false}, nil
+}
+
+type snapshotWriter struct {
+	gobdb.SnapshotWriter
+	faults *Faults
+	closed bool
+}
+
+func (sw *snapshotWriter) Write(writer gobdb.Writer) error {
+	if err := sw.faults.check(OpWrite); err != nil {
+		return err
+	}
+	return sw.SnapshotWriter.Write(writer)
+}
+
+func (sw *snapshotWriter) Close() error {
+	if sw.closed {
+		return errors.New("gobdb: close() on closed SnapshotWriter")
+	}
+	sw.closed = true
+	if err := sw.faults.check(OpClose); err != nil {
+		return err
+	}
+	return sw.SnapshotWriter.Close()
+}
@@ -0,0 +1,191 @@
+// Package gobdbhttp exposes the registered Readers and Writers of a gobdb
+// Database as HTTP endpoints with JSON bodies.
+//
+// The endpoints are:
+//
+//	POST /read/<name>   it decodes the body into the Reader and returns {"value": ...}
+//	POST /write/<name>  it decodes the body into the Writer and returns {"value": ...}
+//	GET  /last          it returns {"id": ...}, the last TransactionId
+//	POST /snapshot      it takes a Snapshot and returns {"id": ...}
+//
+// On failure, they return {"error": "..."} with a 4xx or 5xx status code.
+package gobdbhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+// An optional interface of Databases that know their last TransactionId, like
+// DefaultDatabase.
+type LastIdDatabase interface {
+	gobdb.Database
+	LastId() gobdb.TransactionId
+}
+
+// An http.Handler of a Database. The reads run in parallel, but not with the
+// writes and the Snapshots. The registration is not thread-safe.
+type Gateway struct {
+	mutex       sync.RWMutex
+	database    gobdb.Database
+	readers     map[string]reflect.Type
+	writers     map[string]reflect.Type
+	snapshooter gobdb.Snapshooter
+	repository  gobdb.WriteSnapshotRepository
+	mux         *http.ServeMux
+}
+
+// New instance.
+func NewGateway(database gobdb.Database) *Gateway {
+	g := &Gateway{database: database, readers: make(map[string]reflect.Type), writers: make(map[string]reflect.Type)}
+	g.mux = http.NewServeMux()
+	g.mux.HandleFunc("/read/", g.read)
+	g.mux.HandleFunc("/write/", g.write)
+	g.mux.HandleFunc("/last", g.last)
+	g.mux.HandleFunc("/snapshot", g.snapshot)
+	return g
+}
+
+// It exposes the type of the Reader, a pointer to a struct, with the name. It
+// panics if it is another type.
+func (g *Gateway) RegisterReader(name string, reader gobdb.Reader) {
+	g.readers[name] = structPointerType(reader)
+}
+
+// It exposes the type of the Writer, a pointer to a struct, with the name. It
+// panics if it is another type.
+func (g *Gateway) RegisterWriter(name string, writer gobdb.Writer) {
+	g.writers[name] = structPointerType(writer)
+}
+
+func structPointerType(op interface{}) reflect.Type {
+	t := reflect.TypeOf(op)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("gobdb: %v is not a pointer to a struct", t))
+	}
+	return t
+}
+
+// It enables the endpoint of the Snapshots, if the Database is a
+// SnapshotDatabase.
+func (g *Gateway) SetSnapshots(snapshooter gobdb.Snapshooter, repository gobdb.WriteSnapshotRepository) {
+	g.snapshooter, g.repository = snapshooter, repository
+}
+
+// Implements http.Handler.ServeHTTP().
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+// It decodes the body into a new value of the type registered with the name
+// at the end of the path.
+func (g *Gateway) decode(w http.ResponseWriter, r *http.Request, prefix string, types map[string]reflect.Type) (interface{}, bool) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("gobdb: method %s not allowed", r.Method))
+		return nil, false
+	}
+	name := strings.TrimPrefix(r.URL.Path, prefix)
+	t, ok := types[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("gobdb: %q not registered", name))
+		return nil, false
+	}
+	op := reflect.New(t.Elem()).Interface()
+	if err := json.NewDecoder(r.Body).Decode(op); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+	return op, true
+}
+
+func (g *Gateway) read(w http.ResponseWriter, r *http.Request) {
+	op, ok := g.decode(w, r, "/read/", g.readers)
+	if !ok {
+		return
+	}
+	g.mutex.RLock()
+	value := g.database.Read(op.(gobdb.Reader))
+	g.mutex.RUnlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"value": value})
+}
+
+// The first error of the Writer is 422 Unprocessable Entity and the second
+// one is 500 Internal Server Error.
+func (g *Gateway) write(w http.ResponseWriter, r *http.Request) {
+	database, ok := g.database.(gobdb.WriteDatabase)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, fmt.Errorf("gobdb: the Database is not a WriteDatabase"))
+		return
+	}
+	op, ok := g.decode(w, r, "/write/", g.writers)
+	if !ok {
+		return
+	}
+	g.mutex.Lock()
+	value, err1, err2 := database.Write(op.(gobdb.Writer))
+	g.mutex.Unlock()
+	if err1 != nil {
+		writeError(w, http.StatusUnprocessableEntity, err1)
+		return
+	}
+	if err2 != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"value": value, "error": err2.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"value": value})
+}
+
+func (g *Gateway) last(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("gobdb: method %s not allowed", r.Method))
+		return
+	}
+	database, ok := g.database.(LastIdDatabase)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, fmt.Errorf("gobdb: the Database does not know its last TransactionId"))
+		return
+	}
+	g.mutex.RLock()
+	id := database.LastId()
+	g.mutex.RUnlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": id})
+}
+
+func (g *Gateway) snapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("gobdb: method %s not allowed", r.Method))
+		return
+	}
+	database, ok := g.database.(gobdb.SnapshotDatabase)
+	if !ok || g.snapshooter == nil || g.repository == nil {
+		writeError(w, http.StatusNotImplemented, fmt.Errorf("gobdb: Snapshots not enabled"))
+		return
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if err := database.TakeSnapshot(g.snapshooter, g.repository); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	response := map[string]interface{}{}
+	if database, ok := g.database.(LastIdDatabase); ok {
+		response["id"] = database.LastId()
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]interface{}{"error": err.Error()})
+}
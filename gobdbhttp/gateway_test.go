@@ -0,0 +1,122 @@
+package gobdbhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+type testRoot struct {
+	counter int
+}
+
+type testReader struct {
+	Plus int
+}
+
+func (op *testReader) Read(root gobdb.Root) interface{} {
+	return root.(*testRoot).counter + op.Plus
+}
+
+type testWriter struct {
+	Increment int
+}
+
+func (op *testWriter) Write(root gobdb.Root) (interface{}, error) {
+	r := root.(*testRoot)
+	if op.Increment < 0 {
+		return nil, errors.New("negative increment")
+	}
+	r.counter += op.Increment
+	return r.counter, nil
+}
+
+func testSnapshooter(root gobdb.Root, write func(...gobdb.Writer) error) error {
+	return write(&testWriter{root.(*testRoot).counter})
+}
+
+func init() {
+	gobdb.RegisterWriter(&testWriter{})
+}
+
+func testRequest(t *testing.T, handler http.Handler, method, path, body string, status int) map[string]interface{} {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != status {
+		t.Error(method, path, recorder.Code, recorder.Body.String())
+	}
+	response := map[string]interface{}{}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Error(method, path, err)
+	}
+	return response
+}
+
+func TestGateway(t *testing.T) {
+
+	repository := gobdb.NewMemSnapshotRepository()
+	gateway := NewGateway(gobdb.NewDefaultDatabase(&testRoot{}, 0, nil))
+	gateway.RegisterReader("counter", &testReader{})
+	gateway.RegisterWriter("increment", &testWriter{})
+
+	if r := testRequest(t, gateway, "POST", "/write/increment", `{"Increment": 3}`, 200); r["value"] != 3.0 {
+		t.Error(r)
+	}
+	if r := testRequest(t, gateway, "POST", "/write/increment", `{"Increment": -1}`, 422); r["error"] != "negative increment" {
+		t.Error(r)
+	}
+	if r := testRequest(t, gateway, "POST", "/read/counter", `{"Plus": 10}`, 200); r["value"] != 13.0 {
+		t.Error(r)
+	}
+	if r := testRequest(t, gateway, "GET", "/last", "", 200); r["id"] != 1.0 {
+		t.Error(r)
+	}
+
+	testRequest(t, gateway, "POST", "/read/other", `{}`, 404)
+	testRequest(t, gateway, "POST", "/read/counter", `{`, 400)
+	testRequest(t, gateway, "GET", "/read/counter", "", 405)
+	testRequest(t, gateway, "POST", "/snapshot", "", 501)
+
+	gateway.SetSnapshots(testSnapshooter, repository)
+	if r := testRequest(t, gateway, "POST", "/snapshot", "", 200); r["id"] != 1.0 {
+		t.Error(r)
+	}
+	if snapshots, err := repository.Snapshots(); err != nil || len(snapshots) != 1 || snapshots[0].Id() != 1 {
+		t.Error(snapshots, err)
+	}
+}
+
+// A Reader that is not a pointer.
+type testValueReader struct {
+}
+
+func (op testValueReader) Read(root gobdb.Root) interface{} {
+	return nil
+}
+
+func TestGatewayRegisterNotPointer(t *testing.T) {
+
+	gateway := NewGateway(gobdb.NewDefaultDatabase(&testRoot{}, 0, nil))
+	for _, register := range []func(){
+		func() { gateway.RegisterReader("value", testValueReader{}) },
+		func() { gateway.RegisterReader("nil", nil) },
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error(r)
+				}
+			}()
+			register()
+		}()
+	}
+	if len(gateway.readers) != 0 {
+		t.Error(gateway.readers)
+	}
+}
@@ -0,0 +1,78 @@
+package gobdbraft
+
+import (
+	"errors"
+	"sync"
+)
+
+// A Transport that invokes the handlers of the Nodes in the same process,
+// for tests. The Nodes can be disconnected to simulate failures.
+// Thread-safe.
+type MemTransport struct {
+	mutex        sync.RWMutex
+	nodes        map[string]*Node
+	disconnected map[string]bool
+}
+
+// New instance.
+func NewMemTransport() *MemTransport {
+	return &MemTransport{nodes: make(map[string]*Node), disconnected: make(map[string]bool)}
+}
+
+// It adds a Node.
+func (t *MemTransport) Add(node *Node) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.nodes[node.Id()] = node
+}
+
+// It fails the calls from and to the Node.
+func (t *MemTransport) Disconnect(id string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.disconnected[id] = true
+}
+
+// It undoes Disconnect().
+func (t *MemTransport) Connect(id string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.disconnected, id)
+}
+
+func (t *MemTransport) node(from, to string) (*Node, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	node, ok := t.nodes[to]
+	if !ok || t.disconnected[from] || t.disconnected[to] {
+		return nil, errors.New("gobdb: Node " + to + " unreachable")
+	}
+	return node, nil
+}
+
+// Implements Transport.RequestVote().
+func (t *MemTransport) RequestVote(to string, args *RequestVoteArgs) (*RequestVoteReply, error) {
+	node, err := t.node(args.Candidate, to)
+	if err != nil {
+		return nil, err
+	}
+	return node.HandleRequestVote(args)
+}
+
+// Implements Transport.AppendEntries().
+func (t *MemTransport) AppendEntries(to string, args *AppendEntriesArgs) (*AppendEntriesReply, error) {
+	node, err := t.node(args.Leader, to)
+	if err != nil {
+		return nil, err
+	}
+	return node.HandleAppendEntries(args)
+}
+
+// Implements Transport.InstallSnapshot().
+func (t *MemTransport) InstallSnapshot(to string, args *InstallSnapshotArgs) (*InstallSnapshotReply, error) {
+	node, err := t.node(args.Leader, to)
+	if err != nil {
+		return nil, err
+	}
+	return node.HandleInstallSnapshot(args)
+}
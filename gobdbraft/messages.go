@@ -0,0 +1,216 @@
+package gobdbraft
+
+import (
+	"errors"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+// An entry of the Raft log, whose index is the TransactionId.
+type Entry struct {
+	Term   uint64
+	Writer gobdb.Writer
+}
+
+// The arguments of the RequestVote call.
+type RequestVoteArgs struct {
+	Term         uint64
+	Candidate    string
+	LastLogIndex gobdb.TransactionId
+	LastLogTerm  uint64
+}
+
+// The reply of the RequestVote call.
+type RequestVoteReply struct {
+	Term    uint64
+	Granted bool
+}
+
+// The arguments of the AppendEntries call.
+type AppendEntriesArgs struct {
+	Term         uint64
+	Leader       string
+	PrevLogIndex gobdb.TransactionId
+	PrevLogTerm  uint64
+	Entries      []Entry
+	LeaderCommit gobdb.TransactionId
+}
+
+// The reply of the AppendEntries call. On failure, the last index of the log
+// of the follower is a hint of the next entries to send.
+type AppendEntriesReply struct {
+	Term         uint64
+	Success      bool
+	LastLogIndex gobdb.TransactionId
+}
+
+// The arguments of the InstallSnapshot call, with the Writers of the whole
+// Snapshot.
+type InstallSnapshotArgs struct {
+	Term      uint64
+	Leader    string
+	LastIndex gobdb.TransactionId
+	LastTerm  uint64
+	Writers   []gobdb.Writer
+}
+
+// The reply of the InstallSnapshot call.
+type InstallSnapshotReply struct {
+	Term uint64
+}
+
+// It delivers the calls of a Node to the other ones, usually by invoking their
+// handlers remotely.
+type Transport interface {
+	RequestVote(to string, args *RequestVoteArgs) (*RequestVoteReply, error)
+	AppendEntries(to string, args *AppendEntriesArgs) (*AppendEntriesReply, error)
+	InstallSnapshot(to string, args *InstallSnapshotArgs) (*InstallSnapshotReply, error)
+}
+
+// The handler of the RequestVote call. It saves the current term and vote
+// before it answers.
+func (n *Node) HandleRequestVote(args *RequestVoteArgs) (*RequestVoteReply, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if err := n.stoppedError(); err != nil {
+		return nil, err
+	}
+	if args.Term > n.currentTerm {
+		n.becomeFollower(args.Term)
+	}
+	reply := &RequestVoteReply{Term: n.currentTerm}
+	if args.Term < n.currentTerm {
+		return reply, nil
+	}
+	lastTerm := n.term(n.lastIndex())
+	upToDate := args.LastLogTerm > lastTerm || args.LastLogTerm == lastTerm && args.LastLogIndex >= n.lastIndex()
+	if (n.votedFor == "" || n.votedFor == args.Candidate) && upToDate {
+		n.votedFor, reply.Granted = args.Candidate, true
+		n.resetDeadline()
+	}
+	if err := n.saveState(); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// The handler of the AppendEntries call. It saves the current term and the
+// entries appended to the log before it answers.
+func (n *Node) HandleAppendEntries(args *AppendEntriesArgs) (*AppendEntriesReply, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if err := n.stoppedError(); err != nil {
+		return nil, err
+	}
+	reply := &AppendEntriesReply{Term: n.currentTerm}
+	if args.Term < n.currentTerm {
+		return reply, nil
+	}
+	n.becomeFollower(args.Term)
+	reply.Term, n.leader = n.currentTerm, args.Leader
+	n.resetDeadline()
+	if err := n.saveState(); err != nil {
+		return nil, err
+	}
+
+	// the entries up to the last Snapshot are committed, so they match
+	prev, entries := args.PrevLogIndex, args.Entries
+	if prev < n.snapshotIndex {
+		skip := n.snapshotIndex - prev
+		if gobdb.TransactionId(len(entries)) <= skip {
+			entries = nil
+		} else {
+			entries = entries[skip:]
+		}
+		prev = n.snapshotIndex
+	} else if prev > n.lastIndex() {
+		reply.LastLogIndex = n.lastIndex()
+		return reply, nil
+	} else if prev > n.snapshotIndex && n.term(prev) != args.PrevLogTerm {
+		reply.LastLogIndex = prev - 1
+		return reply, nil
+	}
+
+	for i, entry := range entries {
+		index := prev + 1 + gobdb.TransactionId(i)
+		if index <= n.lastIndex() {
+			if n.term(index) == entry.Term {
+				continue
+			}
+			n.log = n.log[:index-n.snapshotIndex-1]
+			n.failWaiters(index-1, &ErrNotLeader{args.Leader})
+		}
+		n.log, n.unsaved = append(n.log, entry), true
+	}
+	if err := n.saveState(); err != nil {
+		return nil, err
+	}
+
+	last := prev + gobdb.TransactionId(len(entries))
+	if args.LeaderCommit > n.commitIndex {
+		n.commitIndex = args.LeaderCommit
+		if last < n.commitIndex {
+			n.commitIndex = last
+		}
+	}
+	n.apply()
+	reply.Success, reply.LastLogIndex = true, last
+	return reply, nil
+}
+
+// The handler of the InstallSnapshot call. It saves the current term, applies
+// the Writers to a new Root from the Config, writes them to the repository of
+// the Config and saves the rest of the log.
+func (n *Node) HandleInstallSnapshot(args *InstallSnapshotArgs) (*InstallSnapshotReply, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if err := n.stoppedError(); err != nil {
+		return nil, err
+	}
+	reply := &InstallSnapshotReply{n.currentTerm}
+	if args.Term < n.currentTerm {
+		return reply, nil
+	}
+	n.becomeFollower(args.Term)
+	reply.Term, n.leader = n.currentTerm, args.Leader
+	n.resetDeadline()
+	if err := n.saveState(); err != nil {
+		return nil, err
+	}
+	if args.LastIndex <= n.lastApplied {
+		return reply, nil
+	}
+	if n.config.NewRoot == nil {
+		return nil, errors.New("gobdb: InstallSnapshot without NewRoot")
+	}
+
+	root := n.config.NewRoot()
+	for _, writer := range args.Writers {
+		if _, err := writer.Write(root); err != nil {
+			return nil, err
+		}
+	}
+	if n.config.Snapshots != nil {
+		if err := writeSnapshot(n.config.Snapshots, args.LastIndex, args.Writers); err != nil {
+			return nil, err
+		}
+	}
+
+	if args.LastIndex < n.lastIndex() && n.term(args.LastIndex) == args.LastTerm {
+		n.log = n.log[args.LastIndex-n.snapshotIndex:]
+	} else {
+		n.log = nil
+	}
+	n.failWaiters(0, errors.New("gobdb: Transaction replaced by a Snapshot"))
+	n.root = root
+	n.snapshotIndex, n.snapshotTerm = args.LastIndex, args.LastTerm
+	n.lastApplied, n.unsaved = args.LastIndex, true
+	if n.commitIndex < args.LastIndex {
+		n.commitIndex = args.LastIndex
+	}
+	if err := n.saveState(); err != nil {
+		return nil, err
+	}
+	n.apply()
+	return reply, nil
+}
@@ -0,0 +1,614 @@
+// Package gobdbraft replicates the Transactions of a gobdb Database in a
+// cluster of Nodes with the Raft consensus algorithm.
+//
+// The index of the Raft log is the TransactionId. Every Node applies the
+// committed Transactions to its own Root and writes them to its own
+// BurstDispatcher, so it can be recovered with gobdb.Recover(). The Nodes that
+// are too far behind the leader receive a Snapshot of its Root instead.
+//
+// The current term, vote and log are saved in the StateStore of the Config
+// before the Node answers a call or counts an entry as replicated, and loaded
+// again by NewNode(). The whole log is saved every time it changes, so
+// TakeSnapshot() should be invoked from time to time to discard its beginning.
+package gobdbraft
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+// The maximum number of entries sent in one AppendEntries call.
+const maxEntries = 64
+
+// The error returned by the handlers and by Write() when the Node is stopped.
+var ErrStopped = errors.New("gobdb: Node stopped")
+
+// The error returned as the first one by Node.Write() when it is not the
+// leader. It is registered with gob.Register(), so gobdbrpc sends it.
+type ErrNotLeader struct {
+	// The last known leader, empty if unknown.
+	Leader string
+}
+
+func (e *ErrNotLeader) Error() string {
+	return fmt.Sprintf("gobdb: not the leader, the leader is %q", e.Leader)
+}
+
+// The Writer appended by a new leader to commit the entries of the previous
+// terms, and written to the BurstDispatcher instead of a Writer that failed.
+type NoOp struct {
+}
+
+// Implements Writer.Write(). It does nothing.
+func (op *NoOp) Write(root gobdb.Root) (interface{}, error) {
+	return nil, nil
+}
+
+func init() {
+	gobdb.RegisterWriter(&NoOp{})
+	gob.Register(&ErrNotLeader{})
+}
+
+// The configuration of a Node.
+type Config struct {
+	// The id of the Node.
+	Id string
+	// The ids of the other Nodes of the cluster.
+	Peers []string
+	Transport
+	// The minimum time without a leader before an election; the actual one is
+	// random, up to twice it.
+	ElectionTimeout time.Duration
+	// The time between AppendEntries calls of the leader.
+	HeartbeatInterval time.Duration
+	// It takes the Snapshots sent to the Nodes that are behind. Optional, but
+	// TakeSnapshot() requires it.
+	Snapshooter gobdb.Snapshooter
+	// It creates the Roots of the Snapshots received. Optional.
+	NewRoot func() gobdb.Root
+	// The repository of the Snapshots received. Optional, but without it a
+	// Node that receives one can not be restarted with the log of its
+	// StateStore.
+	Snapshots gobdb.WriteSnapshotRepository
+	// It keeps the current term, vote and log. Optional, but without it a Node
+	// that is restarted must not take part in the term it was in.
+	State StateStore
+}
+
+type nodeState int
+
+const (
+	follower nodeState = iota
+	candidate
+	leader
+)
+
+type nodeResult struct {
+	value      interface{}
+	err1, err2 error
+}
+
+// A member of a cluster, a Database and a WriteDatabase. Only the leader
+// accepts Writes. Thread-safe.
+type Node struct {
+	mutex      sync.Mutex
+	config     Config
+	root       gobdb.Root
+	dispatcher gobdb.BurstDispatcher
+
+	state       nodeState
+	currentTerm uint64
+	votedFor    string
+	saved       HardState
+	unsaved     bool
+	leader      string
+	deadline    time.Time
+
+	// log[0] is the entry after snapshotIndex
+	log           []Entry
+	snapshotIndex gobdb.TransactionId
+	snapshotTerm  uint64
+	commitIndex   gobdb.TransactionId
+	lastApplied   gobdb.TransactionId
+
+	nextIndex  map[string]gobdb.TransactionId
+	matchIndex map[string]gobdb.TransactionId
+	inflight   map[string]bool
+	contacted  map[string]time.Time
+	waiters    map[gobdb.TransactionId]chan nodeResult
+
+	stopped bool
+	latched error
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// New instance. The TransactionId is the last one applied to the Root, which
+// must have been committed by the cluster. The BurstDispatcher is optional. It
+// loads the current term, vote and log from the StateStore of the Config; the
+// TransactionId must be in the log or be the one before it, unless the log has
+// never been saved.
+func NewNode(config Config, root gobdb.Root, lastId gobdb.TransactionId, dispatcher gobdb.BurstDispatcher) (*Node, error) {
+	n := &Node{config: config, root: root, dispatcher: dispatcher}
+	n.snapshotIndex, n.commitIndex, n.lastApplied = lastId, lastId, lastId
+	if config.State != nil {
+		state, err := config.State.LoadState()
+		if err != nil {
+			return nil, err
+		}
+		n.currentTerm, n.votedFor, n.saved = state.Term, state.VotedFor, state
+		if state.LogIndex != 0 || len(state.Log) != 0 {
+			if lastId < state.LogIndex || lastId > state.LogIndex+gobdb.TransactionId(len(state.Log)) {
+				return nil, fmt.Errorf("gobdb: TransactionId %d out of the log saved, from %d to %d", lastId, state.LogIndex, state.LogIndex+gobdb.TransactionId(len(state.Log)))
+			}
+			n.snapshotIndex, n.snapshotTerm, n.log = state.LogIndex, state.LogTerm, state.Log
+		}
+	}
+	n.nextIndex = make(map[string]gobdb.TransactionId)
+	n.matchIndex = make(map[string]gobdb.TransactionId)
+	n.inflight = make(map[string]bool)
+	n.contacted = make(map[string]time.Time)
+	n.waiters = make(map[gobdb.TransactionId]chan nodeResult)
+	n.stop, n.done = make(chan struct{}), make(chan struct{})
+	return n, nil
+}
+
+// The id of the Node.
+func (n *Node) Id() string {
+	return n.config.Id
+}
+
+// It starts the timers of the elections and heartbeats.
+func (n *Node) Start() {
+	n.mutex.Lock()
+	n.resetDeadline()
+	n.mutex.Unlock()
+	go n.run()
+}
+
+// It stops the timers and fails the pending Writes. The BurstDispatcher is not
+// closed.
+func (n *Node) Stop() {
+	close(n.stop)
+	<-n.done
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.stopped = true
+	n.state = follower
+	n.failWaiters(0, ErrStopped)
+}
+
+// The last known leader, empty if unknown.
+func (n *Node) Leader() string {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.leader
+}
+
+// It returns whether it is the leader.
+func (n *Node) IsLeader() bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.state == leader
+}
+
+// The last TransactionId applied to the Root.
+func (n *Node) LastId() gobdb.TransactionId {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.lastApplied
+}
+
+// Implements Database.Read(). It reads the local Root, which may be behind the
+// leader.
+func (n *Node) Read(reader gobdb.Reader) interface{} {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return reader.Read(n.root)
+}
+
+// Implements WriteDatabase.Write(). It appends the Writer to the log and waits
+// until it is committed and applied to the Root. The first error is an
+// *ErrNotLeader if the Node is not the leader. If the entry is overwritten by
+// another leader, the Node loses the leadership or the Node is stopped, the
+// second error is not nil and the Writer may or may not have been committed.
+// After an error of the BurstDispatcher, the Node is latched, it does not take
+// part in the cluster anymore and the first error is a *gobdb.ErrLatched. A
+// leader that has not heard from a majority of the cluster for twice the
+// ElectionTimeout loses the leadership.
+func (n *Node) Write(writer gobdb.Writer) (interface{}, error, error) {
+	n.mutex.Lock()
+	if err := n.stoppedError(); err != nil {
+		n.mutex.Unlock()
+		return nil, err, nil
+	}
+	if n.state != leader {
+		err := &ErrNotLeader{n.leader}
+		n.mutex.Unlock()
+		return nil, err, nil
+	}
+	n.log, n.unsaved = append(n.log, Entry{n.currentTerm, writer}), true
+	if err := n.saveState(); err != nil {
+		n.log = n.log[:len(n.log)-1]
+		n.mutex.Unlock()
+		return nil, nil, err
+	}
+	waiter := make(chan nodeResult, 1)
+	n.waiters[n.lastIndex()] = waiter
+	n.advanceCommit()
+	n.mutex.Unlock()
+
+	n.broadcast()
+	result := <-waiter
+	return result.value, result.err1, result.err2
+}
+
+// It writes a Snapshot of the Root with the Snapshooter of the Config into the
+// repository and discards the entries of the log that it contains, then it
+// saves the log.
+func (n *Node) TakeSnapshot(repository gobdb.WriteSnapshotRepository) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	writers, err := n.snapshot()
+	if err != nil {
+		return err
+	}
+	if err := writeSnapshot(repository, n.lastApplied, writers); err != nil {
+		return err
+	}
+	n.snapshotTerm = n.term(n.lastApplied)
+	n.log = n.log[n.lastApplied-n.snapshotIndex:]
+	n.snapshotIndex, n.unsaved = n.lastApplied, true
+	return n.saveState()
+}
+
+func (n *Node) run() {
+	defer close(n.done)
+	ticker := time.NewTicker(n.config.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+		}
+		n.mutex.Lock()
+		if n.state == leader && !n.hasQuorum() {
+			n.becomeFollower(n.currentTerm)
+		}
+		state, expired := n.state, time.Now().After(n.deadline)
+		n.mutex.Unlock()
+		if state == leader {
+			n.broadcast()
+		} else if expired {
+			n.elect()
+		}
+	}
+}
+
+// It must be invoked with the mutex.
+func (n *Node) resetDeadline() {
+	timeout := n.config.ElectionTimeout
+	n.deadline = time.Now().Add(timeout + time.Duration(rand.Int63n(int64(timeout)+1)))
+}
+
+// It must be invoked with the mutex.
+func (n *Node) lastIndex() gobdb.TransactionId {
+	return n.snapshotIndex + gobdb.TransactionId(len(n.log))
+}
+
+// The term of an entry of the log, zero if it is before the last Snapshot. It
+// must be invoked with the mutex.
+func (n *Node) term(index gobdb.TransactionId) uint64 {
+	if index < n.snapshotIndex {
+		return 0
+	}
+	if index == n.snapshotIndex {
+		return n.snapshotTerm
+	}
+	return n.log[index-n.snapshotIndex-1].Term
+}
+
+// It fails the pending Writes if it was the leader. It must be invoked with
+// the mutex.
+func (n *Node) becomeFollower(term uint64) {
+	if term > n.currentTerm {
+		n.currentTerm, n.votedFor, n.leader = term, "", ""
+	}
+	if n.state == leader {
+		n.leader = ""
+		n.resetDeadline()
+		n.failWaiters(n.commitIndex, &ErrNotLeader{})
+	}
+	n.state = follower
+}
+
+// It returns whether a majority of the cluster, including the Node, has
+// answered it within twice the ElectionTimeout. It must be invoked with the
+// mutex.
+func (n *Node) hasQuorum() bool {
+	count, since := 1, time.Now().Add(-2*n.config.ElectionTimeout)
+	for _, peer := range n.config.Peers {
+		if n.contacted[peer].After(since) {
+			count++
+		}
+	}
+	return count > (len(n.config.Peers)+1)/2
+}
+
+// It saves the current term, vote and log in the StateStore of the Config if
+// they have changed. It must be invoked with the mutex.
+func (n *Node) saveState() error {
+	if n.config.State == nil || !n.unsaved && n.currentTerm == n.saved.Term && n.votedFor == n.saved.VotedFor {
+		return nil
+	}
+	log := append([]Entry(nil), n.log...)
+	state := HardState{n.currentTerm, n.votedFor, n.snapshotIndex, n.snapshotTerm, log}
+	if err := n.config.State.SaveState(state); err != nil {
+		return err
+	}
+	n.saved, n.unsaved = state, false
+	return nil
+}
+
+// It remains a candidate if the log can not be saved. It must be invoked with
+// the mutex.
+func (n *Node) becomeLeader() {
+	n.log, n.unsaved = append(n.log, Entry{n.currentTerm, &NoOp{}}), true
+	if err := n.saveState(); err != nil {
+		n.log = n.log[:len(n.log)-1]
+		return
+	}
+	n.state, n.leader = leader, n.config.Id
+	now := time.Now()
+	for _, peer := range n.config.Peers {
+		n.nextIndex[peer], n.matchIndex[peer] = n.lastIndex(), 0
+		n.contacted[peer] = now
+	}
+	n.advanceCommit()
+}
+
+// It starts an election.
+func (n *Node) elect() {
+	n.mutex.Lock()
+	if n.stoppedError() != nil {
+		n.mutex.Unlock()
+		return
+	}
+	n.currentTerm++
+	n.state, n.votedFor, n.leader = candidate, n.config.Id, ""
+	n.resetDeadline()
+	if err := n.saveState(); err != nil {
+		n.state = follower
+		n.mutex.Unlock()
+		return
+	}
+	votes, quorum := 1, len(n.config.Peers)/2+1
+	if votes >= quorum {
+		n.becomeLeader()
+	}
+	args := &RequestVoteArgs{n.currentTerm, n.config.Id, n.lastIndex(), n.term(n.lastIndex())}
+	n.mutex.Unlock()
+
+	for _, peer := range n.config.Peers {
+		go func(peer string) {
+			reply, err := n.config.Transport.RequestVote(peer, args)
+			if err != nil {
+				return
+			}
+			n.mutex.Lock()
+			defer n.mutex.Unlock()
+			if reply.Term > n.currentTerm {
+				n.becomeFollower(reply.Term)
+			} else if reply.Granted && n.state == candidate && n.currentTerm == args.Term {
+				if votes++; votes >= quorum {
+					n.becomeLeader()
+					go n.broadcast()
+				}
+			}
+		}(peer)
+	}
+}
+
+// It replicates the log to every peer.
+func (n *Node) broadcast() {
+	for _, peer := range n.config.Peers {
+		go n.replicate(peer)
+	}
+}
+
+// It sends the entries of the log, or a Snapshot, that the peer needs, until
+// it has all of them.
+func (n *Node) replicate(peer string) {
+	for {
+		n.mutex.Lock()
+		if n.stoppedError() != nil || n.state != leader || n.inflight[peer] {
+			n.mutex.Unlock()
+			return
+		}
+		if n.nextIndex[peer] <= n.snapshotIndex {
+			n.installSnapshot(peer)
+			return
+		}
+		n.inflight[peer] = true
+		prev := n.nextIndex[peer] - 1
+		entries := n.log[prev-n.snapshotIndex:]
+		if len(entries) > maxEntries {
+			entries = entries[:maxEntries]
+		}
+		args := &AppendEntriesArgs{n.currentTerm, n.config.Id, prev, n.term(prev), append([]Entry(nil), entries...), n.commitIndex}
+		n.mutex.Unlock()
+
+		reply, err := n.config.Transport.AppendEntries(peer, args)
+
+		n.mutex.Lock()
+		n.inflight[peer] = false
+		again := false
+		if err == nil {
+			n.contacted[peer] = time.Now()
+			if reply.Term > n.currentTerm {
+				n.becomeFollower(reply.Term)
+			} else if n.state == leader && n.currentTerm == args.Term {
+				if reply.Success {
+					if match := prev + gobdb.TransactionId(len(args.Entries)); match > n.matchIndex[peer] {
+						n.matchIndex[peer] = match
+					}
+					n.nextIndex[peer] = n.matchIndex[peer] + 1
+					n.advanceCommit()
+					again = n.nextIndex[peer] <= n.lastIndex()
+				} else {
+					next := prev
+					if reply.LastLogIndex+1 < next {
+						next = reply.LastLogIndex + 1
+					}
+					if next < 1 {
+						next = 1
+					}
+					n.nextIndex[peer] = next
+					again = true
+				}
+			}
+		}
+		n.mutex.Unlock()
+		if !again {
+			return
+		}
+	}
+}
+
+// It sends a Snapshot of the Root to the peer. It must be invoked with the
+// mutex, that it releases.
+func (n *Node) installSnapshot(peer string) {
+	writers, err := n.snapshot()
+	if err != nil {
+		n.mutex.Unlock()
+		return
+	}
+	n.inflight[peer] = true
+	args := &InstallSnapshotArgs{n.currentTerm, n.config.Id, n.lastApplied, n.term(n.lastApplied), writers}
+	n.mutex.Unlock()
+
+	reply, err := n.config.Transport.InstallSnapshot(peer, args)
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.inflight[peer] = false
+	if err != nil {
+		return
+	}
+	n.contacted[peer] = time.Now()
+	if reply.Term > n.currentTerm {
+		n.becomeFollower(reply.Term)
+	} else if n.state == leader && n.currentTerm == args.Term {
+		if args.LastIndex > n.matchIndex[peer] {
+			n.matchIndex[peer] = args.LastIndex
+		}
+		n.nextIndex[peer] = n.matchIndex[peer] + 1
+		go n.replicate(peer)
+	}
+}
+
+// The Writers of a Snapshot of the Root. It must be invoked with the mutex.
+func (n *Node) snapshot() ([]gobdb.Writer, error) {
+	if n.config.Snapshooter == nil {
+		return nil, errors.New("gobdb: Snapshot without Snapshooter")
+	}
+	var writers []gobdb.Writer
+	err := n.config.Snapshooter(n.root, func(w ...gobdb.Writer) error {
+		writers = append(writers, w...)
+		return nil
+	})
+	return writers, err
+}
+
+// It commits the entries of the current term replicated in a majority and
+// applies them. It must be invoked with the mutex.
+func (n *Node) advanceCommit() {
+	for index := n.lastIndex(); index > n.commitIndex && n.term(index) == n.currentTerm; index-- {
+		count := 1
+		for _, peer := range n.config.Peers {
+			if n.matchIndex[peer] >= index {
+				count++
+			}
+		}
+		if count > (len(n.config.Peers)+1)/2 {
+			n.commitIndex = index
+			break
+		}
+	}
+	n.apply()
+}
+
+// It applies the committed entries to the Root and writes them to the
+// BurstDispatcher. If that fails, the next entries would leave a gap in the
+// Bursts, so the Node is latched: it becomes a follower that does not take part
+// in the cluster and it fails the pending Writes, but it can still be read. It
+// must be restarted from its Bursts and StateStore. It must be invoked with the
+// mutex.
+func (n *Node) apply() {
+	for n.lastApplied < n.commitIndex {
+		n.lastApplied++
+		writer := n.log[n.lastApplied-n.snapshotIndex-1].Writer
+		var result nodeResult
+		result.value, result.err1 = writer.Write(n.root)
+		if result.err1 != nil {
+			writer = &NoOp{}
+		}
+		if n.dispatcher != nil {
+			result.err2 = n.dispatcher.Write(gobdb.Transaction{Id: n.lastApplied, Writer: writer})
+		}
+		if waiter, ok := n.waiters[n.lastApplied]; ok {
+			waiter <- result
+			delete(n.waiters, n.lastApplied)
+		}
+		if result.err2 != nil {
+			n.latched = result.err2
+			n.state, n.leader = follower, ""
+			n.failWaiters(0, n.stoppedError())
+			return
+		}
+	}
+}
+
+// ErrStopped if the Node is stopped, a *gobdb.ErrLatched if it is latched, nil
+// otherwise. It must be invoked with the mutex.
+func (n *Node) stoppedError() error {
+	if n.latched != nil {
+		return &gobdb.ErrLatched{Err: n.latched}
+	}
+	if n.stopped {
+		return ErrStopped
+	}
+	return nil
+}
+
+// It fails the Writes of the entries after the given one, whose result is
+// unknown. It must be invoked with the mutex.
+func (n *Node) failWaiters(after gobdb.TransactionId, err error) {
+	for index, waiter := range n.waiters {
+		if index > after {
+			waiter <- nodeResult{nil, nil, err}
+			delete(n.waiters, index)
+		}
+	}
+}
+
+func writeSnapshot(repository gobdb.WriteSnapshotRepository, id gobdb.TransactionId, writers []gobdb.Writer) error {
+	writer, err := repository.WriteSnapshot(id)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+	for _, w := range writers {
+		if err := writer.Write(w); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
@@ -0,0 +1,431 @@
+package gobdbraft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+type testRoot struct {
+	counter int
+}
+
+type testReader struct {
+}
+
+func (op *testReader) Read(root gobdb.Root) interface{} {
+	return root.(*testRoot).counter
+}
+
+type testWriter struct {
+	Increment int
+}
+
+func (op *testWriter) Write(root gobdb.Root) (interface{}, error) {
+	r := root.(*testRoot)
+	r.counter += op.Increment
+	return r.counter, nil
+}
+
+func testSnapshooter(root gobdb.Root, write func(...gobdb.Writer) error) error {
+	return write(&testWriter{root.(*testRoot).counter})
+}
+
+func init() {
+	gobdb.RegisterWriter(&testWriter{})
+}
+
+type testNode struct {
+	*Node
+	bursts     *gobdb.MemBurstRepository
+	snapshots  *gobdb.MemSnapshotRepository
+	dispatcher gobdb.BurstDispatcher
+}
+
+func testCluster(n int) (*MemTransport, []*testNode) {
+	transport := NewMemTransport()
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("node%d", i)
+	}
+	nodes := make([]*testNode, n)
+	for i, id := range ids {
+		peers := append(append([]string{}, ids[:i]...), ids[i+1:]...)
+		bursts, snapshots := gobdb.NewMemBurstRepository(), gobdb.NewMemSnapshotRepository()
+		dispatcher := gobdb.NewDefaultBurstDispatcher(bursts)
+		config := Config{id, peers, transport, 50 * time.Millisecond, 10 * time.Millisecond,
+			testSnapshooter, func() gobdb.Root { return &testRoot{} }, snapshots, NewMemStateStore()}
+		node, err := NewNode(config, &testRoot{}, 0, dispatcher)
+		if err != nil {
+			panic(err)
+		}
+		transport.Add(node)
+		nodes[i] = &testNode{node, bursts, snapshots, dispatcher}
+	}
+	for _, node := range nodes {
+		node.Start()
+	}
+	return transport, nodes
+}
+
+func testStop(t *testing.T, nodes []*testNode) {
+	for _, node := range nodes {
+		node.Stop()
+		if err := node.dispatcher.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func testEventually(t *testing.T, condition func() bool) {
+	for deadline := time.Now().Add(5 * time.Second); !condition(); {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// It waits for a leader among the given Nodes.
+func testLeader(t *testing.T, nodes ...*testNode) *testNode {
+	var leader *testNode
+	testEventually(t, func() bool {
+		for _, node := range nodes {
+			if node.IsLeader() {
+				leader = node
+				return true
+			}
+		}
+		return false
+	})
+	return leader
+}
+
+// It writes with the leader, retrying if it changes.
+func testWrite(t *testing.T, nodes []*testNode, increment int) {
+	testEventually(t, func() bool {
+		_, err1, err2 := testLeader(t, nodes...).Write(&testWriter{increment})
+		return err1 == nil && err2 == nil
+	})
+}
+
+func testConverge(t *testing.T, nodes []*testNode, counter int) {
+	testEventually(t, func() bool {
+		for _, node := range nodes {
+			if node.Read(&testReader{}) != counter || node.LastId() != nodes[0].LastId() {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func testRecover(t *testing.T, node *testNode, counter int) {
+	root := &testRoot{}
+	var id gobdb.TransactionId
+	if _, err := gobdb.Recover(context.Background(), root, node.snapshots, node.bursts, &id, nil); err != nil {
+		t.Error(node.Id(), err)
+	}
+	if root.counter != counter {
+		t.Error(node.Id(), root.counter)
+	}
+}
+
+func TestNodeInterface(t *testing.T) {
+
+	node, err := NewNode(Config{}, nil, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var i interface{} = node
+	if _, ok := i.(gobdb.WriteDatabase); !ok {
+		t.Error(i)
+	}
+	if _, ok := interface{}(NewMemTransport()).(Transport); !ok {
+		t.Error(i)
+	}
+}
+
+func TestNodeSingle(t *testing.T) {
+
+	_, nodes := testCluster(1)
+	defer testStop(t, nodes)
+	leader := testLeader(t, nodes...)
+	if value, err1, err2 := leader.Write(&testWriter{3}); value != 3 || err1 != nil || err2 != nil {
+		t.Error(value, err1, err2)
+	}
+}
+
+func TestNodeFailover(t *testing.T) {
+
+	transport, nodes := testCluster(3)
+	leader := testLeader(t, nodes...)
+	for i := 1; i <= 5; i++ {
+		testWrite(t, nodes, i)
+	}
+	testConverge(t, nodes, 15)
+
+	for _, node := range nodes {
+		if node != leader {
+			_, err1, _ := node.Write(&testWriter{1})
+			if e, ok := err1.(*ErrNotLeader); !ok || e.Leader != leader.Id() {
+				t.Error(err1)
+			}
+		}
+	}
+
+	transport.Disconnect(leader.Id())
+	others := []*testNode{}
+	for _, node := range nodes {
+		if node != leader {
+			others = append(others, node)
+		}
+	}
+	if testLeader(t, others...) == leader {
+		t.Error(leader.Id())
+	}
+	testWrite(t, others, 10)
+	testConverge(t, others, 25)
+
+	transport.Connect(leader.Id())
+	testConverge(t, nodes, 25)
+	testStop(t, nodes)
+	for _, node := range nodes {
+		testRecover(t, node, 25)
+	}
+}
+
+func TestNodeInstallSnapshot(t *testing.T) {
+
+	transport, nodes := testCluster(3)
+	leader := testLeader(t, nodes...)
+	testWrite(t, nodes, 1)
+	testConverge(t, nodes, 1)
+
+	var behind *testNode
+	for _, node := range nodes {
+		if node != leader {
+			behind = node
+			break
+		}
+	}
+	transport.Disconnect(behind.Id())
+	for i := 2; i <= 5; i++ {
+		if _, err1, err2 := leader.Write(&testWriter{i}); err1 != nil || err2 != nil {
+			t.Fatal(err1, err2)
+		}
+	}
+	if err := leader.TakeSnapshot(gobdb.NewMemSnapshotRepository()); err != nil {
+		t.Error(err)
+	}
+	if _, err1, err2 := leader.Write(&testWriter{6}); err1 != nil || err2 != nil {
+		t.Fatal(err1, err2)
+	}
+
+	transport.Connect(behind.Id())
+	testConverge(t, nodes, 21)
+	snapshots, err := behind.snapshots.Snapshots()
+	if err != nil || len(snapshots) != 1 {
+		t.Error(snapshots, err)
+	}
+	testStop(t, nodes)
+	for _, node := range nodes {
+		testRecover(t, node, 21)
+	}
+}
+
+func TestNodeRestartKeepsVote(t *testing.T) {
+
+	state := NewMemStateStore()
+	config := Config{Id: "a", Peers: []string{"b", "c"}, Transport: NewMemTransport(), ElectionTimeout: time.Second, HeartbeatInterval: time.Second, State: state}
+	node, err := NewNode(config, &testRoot{}, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := node.HandleRequestVote(&RequestVoteArgs{3, "b", 0, 0}); err != nil || !reply.Granted {
+		t.Error(reply, err)
+	}
+	if s, err := state.LoadState(); err != nil || !reflect.DeepEqual(s, HardState{Term: 3, VotedFor: "b"}) {
+		t.Error(s, err)
+	}
+
+	node, err = NewNode(config, &testRoot{}, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := node.HandleRequestVote(&RequestVoteArgs{3, "c", 0, 0}); err != nil || reply.Granted || reply.Term != 3 {
+		t.Error(reply, err)
+	}
+	if reply, err := node.HandleRequestVote(&RequestVoteArgs{3, "b", 0, 0}); err != nil || !reply.Granted {
+		t.Error(reply, err)
+	}
+	if reply, err := node.HandleAppendEntries(&AppendEntriesArgs{4, "c", 0, 0, nil, 0}); err != nil || !reply.Success {
+		t.Error(reply, err)
+	}
+	if s, err := state.LoadState(); err != nil || !reflect.DeepEqual(s, HardState{Term: 4}) {
+		t.Error(s, err)
+	}
+}
+
+func TestNodeRestartKeepsLog(t *testing.T) {
+
+	state := NewMemStateStore()
+	config := Config{Id: "a", Peers: []string{"b", "c"}, Transport: NewMemTransport(), ElectionTimeout: time.Second, HeartbeatInterval: time.Second, State: state}
+	root := &testRoot{}
+	node, err := NewNode(config, root, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []Entry{{1, &testWriter{1}}, {1, &testWriter{2}}, {2, &testWriter{3}}, {2, &testWriter{4}}}
+	if reply, err := node.HandleAppendEntries(&AppendEntriesArgs{2, "b", 0, 0, entries, 3}); err != nil || !reply.Success {
+		t.Error(reply, err)
+	}
+	if s, err := state.LoadState(); err != nil || len(s.Log) != 4 {
+		t.Error(s, err)
+	}
+	if root.counter != 6 {
+		t.Error(root.counter)
+	}
+
+	if _, err := NewNode(config, &testRoot{}, 5, nil); err == nil {
+		t.Error(err)
+	}
+	node, err = NewNode(config, &testRoot{6}, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := node.HandleRequestVote(&RequestVoteArgs{4, "c", 9, 1}); err != nil || reply.Granted {
+		t.Error(reply, err)
+	}
+	if reply, err := node.HandleRequestVote(&RequestVoteArgs{5, "c", 3, 2}); err != nil || reply.Granted {
+		t.Error(reply, err)
+	}
+	if reply, err := node.HandleRequestVote(&RequestVoteArgs{6, "c", 4, 2}); err != nil || !reply.Granted {
+		t.Error(reply, err)
+	}
+}
+
+// A StateStore that fails to save.
+type testFailStateStore struct {
+	MemStateStore
+}
+
+func (s *testFailStateStore) SaveState(state HardState) error {
+	return errors.New("test")
+}
+
+func TestNodeSaveStateError(t *testing.T) {
+
+	config := Config{Id: "a", Peers: []string{"b", "c"}, Transport: NewMemTransport(), ElectionTimeout: time.Second, HeartbeatInterval: time.Second, State: &testFailStateStore{}}
+	node, err := NewNode(config, &testRoot{}, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := node.HandleRequestVote(&RequestVoteArgs{3, "b", 0, 0}); err == nil {
+		t.Error(reply, err)
+	}
+	if reply, err := node.HandleAppendEntries(&AppendEntriesArgs{4, "c", 0, 0, nil, 0}); err == nil {
+		t.Error(reply, err)
+	}
+}
+
+func TestFileStateStore(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewFileStateStore(gobdb.OSFileSystem{}, filepath.Join(dir, "state"))
+	if state, err := store.LoadState(); err != nil || !reflect.DeepEqual(state, HardState{}) {
+		t.Error(state, err)
+	}
+	for _, state := range []HardState{{1, "a", 0, 0, nil}, {2, "", 3, 1, []Entry{{2, &NoOp{}}}}} {
+		if err := store.SaveState(state); err != nil {
+			t.Error(err)
+		}
+		if loaded, err := NewFileStateStore(gobdb.OSFileSystem{}, filepath.Join(dir, "state")).LoadState(); err != nil || !reflect.DeepEqual(loaded, state) {
+			t.Error(loaded, err)
+		}
+	}
+	if files, err := ioutil.ReadDir(dir); err != nil || len(files) != 1 {
+		t.Error(files, err)
+	}
+}
+
+func TestNodeLosesQuorum(t *testing.T) {
+
+	transport, nodes := testCluster(3)
+	defer testStop(t, nodes)
+	leader := testLeader(t, nodes...)
+	testWrite(t, nodes, 1)
+
+	for _, node := range nodes {
+		if node != leader {
+			transport.Disconnect(node.Id())
+		}
+	}
+	if value, err1, err2 := leader.Write(&testWriter{1}); value != nil || err1 != nil || err2 == nil {
+		t.Error(value, err1, err2)
+	}
+	if leader.IsLeader() {
+		t.Error(leader.Id())
+	}
+}
+
+// A BurstDispatcher that fails to write.
+type testFailDispatcher struct {
+}
+
+func (d *testFailDispatcher) Write(transaction gobdb.Transaction) error {
+	return errors.New("test")
+}
+
+func (d *testFailDispatcher) Rotate() error {
+	return nil
+}
+
+func (d *testFailDispatcher) Close() error {
+	return nil
+}
+
+func TestNodeLatched(t *testing.T) {
+
+	config := Config{Id: "a", Peers: []string{"b", "c"}, Transport: NewMemTransport(), ElectionTimeout: time.Second, HeartbeatInterval: time.Second, State: NewMemStateStore()}
+	node, err := NewNode(config, &testRoot{}, 0, &testFailDispatcher{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []Entry{{1, &testWriter{1}}, {1, &testWriter{2}}}
+	if reply, err := node.HandleAppendEntries(&AppendEntriesArgs{1, "b", 0, 0, entries, 2}); err != nil || !reply.Success {
+		t.Error(reply, err)
+	}
+	if id := node.LastId(); id != 1 {
+		t.Error(id)
+	}
+	if reply, err := node.HandleAppendEntries(&AppendEntriesArgs{1, "b", 2, 1, nil, 2}); err == nil {
+		t.Error(reply, err)
+	} else if _, ok := err.(*gobdb.ErrLatched); !ok {
+		t.Error(err)
+	}
+	if reply, err := node.HandleRequestVote(&RequestVoteArgs{2, "c", 2, 1}); err == nil {
+		t.Error(reply, err)
+	}
+	if _, err1, _ := node.Write(&testWriter{1}); err1 == nil {
+		t.Error(err1)
+	} else if _, ok := err1.(*gobdb.ErrLatched); !ok {
+		t.Error(err1)
+	}
+	if value := node.Read(&testReader{}); value != 1 {
+		t.Error(value)
+	}
+}
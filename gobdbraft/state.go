@@ -0,0 +1,112 @@
+package gobdbraft
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+// The current term, vote and log of a Node, that it must not forget when it is
+// restarted.
+type HardState struct {
+	Term     uint64
+	VotedFor string
+	// The index and term of the last entry before the log, which is already
+	// in the Snapshots or Bursts of the Node.
+	LogIndex gobdb.TransactionId
+	LogTerm  uint64
+	Log      []Entry
+}
+
+// It keeps the HardState of a Node in stable storage.
+type StateStore interface {
+	// The last HardState saved, the zero one if there is none.
+	LoadState() (HardState, error)
+	// It returns once the HardState is in stable storage.
+	SaveState(state HardState) error
+}
+
+// A StateStore in memory, for tests. It survives the Nodes, but not the
+// process. Thread-safe.
+type MemStateStore struct {
+	mutex sync.Mutex
+	state HardState
+}
+
+// New instance.
+func NewMemStateStore() *MemStateStore {
+	return &MemStateStore{}
+}
+
+// Implements StateStore.LoadState().
+func (s *MemStateStore) LoadState() (HardState, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.state, nil
+}
+
+// Implements StateStore.SaveState().
+func (s *MemStateStore) SaveState(state HardState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.state = state
+	return nil
+}
+
+// A StateStore in a file of a FileSystem. Every HardState is written to a
+// temporary file that replaces the previous one, so a crash leaves one of
+// them. No thread-safe.
+type FileStateStore struct {
+	fs   gobdb.FileSystem
+	path string
+}
+
+// New instance.
+func NewFileStateStore(fs gobdb.FileSystem, path string) *FileStateStore {
+	return &FileStateStore{fs, path}
+}
+
+// Implements StateStore.LoadState().
+func (s *FileStateStore) LoadState() (state HardState, err error) {
+	file, err := s.fs.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	defer file.Close()
+	err = gob.NewDecoder(file).Decode(&state)
+	return
+}
+
+// Implements StateStore.SaveState().
+func (s *FileStateStore) SaveState(state HardState) (err error) {
+	dir := filepath.Dir(s.path)
+	file, err := s.fs.CreateTemp(dir, "tmp-state-")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			s.fs.Remove(file.Name())
+		}
+	}()
+	err = gob.NewEncoder(file).Encode(&state)
+	if err == nil {
+		err = file.Sync()
+	}
+	if err2 := file.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return
+	}
+	if err = s.fs.Rename(file.Name(), s.path); err != nil {
+		return
+	}
+	return s.fs.SyncDir(dir)
+}
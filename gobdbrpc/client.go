@@ -0,0 +1,73 @@
+package gobdbrpc
+
+import (
+	"net/rpc"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+// A Database and WriteDatabase that calls a remote Server.
+// Thread-safe.
+type Client struct {
+	client *rpc.Client
+}
+
+// New instance.
+func NewClient(client *rpc.Client) *Client {
+	return &Client{client}
+}
+
+// New instance connected to the Server at the address, see net.Dial().
+func Dial(network, address string) (*Client, error) {
+	client, err := rpc.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{client}, nil
+}
+
+// The value returned by Client.Read() when the call fails, which a Reader
+// can not return because its type is not registered with gob.Register().
+type ErrCall struct {
+	Err error
+}
+
+func (e *ErrCall) Error() string {
+	return "gobdb: remote call failed: " + e.Err.Error()
+}
+
+// Implements Database.Read(). If the call fails, it returns an *ErrCall as
+// the value. TryRead() tells the errors apart from the values instead.
+func (c *Client) Read(reader gobdb.Reader) interface{} {
+	value, err := c.TryRead(reader)
+	if err != nil {
+		return &ErrCall{err}
+	}
+	return value
+}
+
+// It applies the Reader to the remote Database and returns the error of the
+// call, if it fails. It is the way to read from a Client that is not used as
+// a Database.
+func (c *Client) TryRead(reader gobdb.Reader) (interface{}, error) {
+	var reply ReadReply
+	if err := c.client.Call(ServiceName+".Read", &ReadArgs{reader}, &reply); err != nil {
+		return nil, err
+	}
+	return reply.Value, nil
+}
+
+// Implements WriteDatabase.Write(). If the call fails, it returns its error as
+// the second one, because the Writer may have been applied.
+func (c *Client) Write(writer gobdb.Writer) (interface{}, error, error) {
+	var reply WriteReply
+	if err := c.client.Call(ServiceName+".Write", &WriteArgs{writer}, &reply); err != nil {
+		return nil, nil, err
+	}
+	return reply.Value, reply.Err1, reply.Err2
+}
+
+// It closes the connection.
+func (c *Client) Close() error {
+	return c.client.Close()
+}
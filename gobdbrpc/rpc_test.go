@@ -0,0 +1,192 @@
+package gobdbrpc
+
+import (
+	"encoding/gob"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+	"github.com/daniel-fanjul-alcuten/gobdb/gobdbraft"
+)
+
+type testRoot struct {
+	counter int
+}
+
+type testReader struct {
+}
+
+func (op *testReader) Read(root gobdb.Root) interface{} {
+	return root.(*testRoot).counter
+}
+
+type testWriter struct {
+	Increment int
+}
+
+func (op *testWriter) Write(root gobdb.Root) (interface{}, error) {
+	r := root.(*testRoot)
+	if op.Increment < 0 {
+		return nil, errors.New("negative increment")
+	}
+	r.counter += op.Increment
+	return r.counter, nil
+}
+
+func init() {
+	gob.Register(&testReader{})
+	gobdb.RegisterWriter(&testWriter{})
+}
+
+func TestClientInterface(t *testing.T) {
+
+	var i interface{} = NewClient(nil)
+	if _, ok := i.(gobdb.Database); !ok {
+		t.Error(i)
+	}
+	if _, ok := i.(gobdb.WriteDatabase); !ok {
+		t.Error(i)
+	}
+}
+
+func testServe(t *testing.T, database gobdb.Database) (*Client, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go NewServer(database).Serve(listener)
+	client, err := Dial("tcp", listener.Addr().String())
+	if err != nil {
+		listener.Close()
+		t.Fatal(err)
+	}
+	return client, func() {
+		client.Close()
+		listener.Close()
+	}
+}
+
+func TestClient(t *testing.T) {
+
+	repository := gobdb.NewMemBurstRepository()
+	dispatcher := gobdb.NewDefaultBurstDispatcher(repository)
+	client, stop := testServe(t, gobdb.NewDefaultDatabase(&testRoot{}, 0, dispatcher))
+	defer stop()
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err1, err2 := client.Write(&testWriter{i}); err1 != nil || err2 != nil {
+				t.Error(err1, err2)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if value := client.Read(&testReader{}); value != 55 {
+		t.Error(value)
+	}
+	if value, err1, err2 := client.Write(&testWriter{-1}); value != nil || err1 == nil || err2 != nil {
+		t.Error(value, err1, err2)
+	}
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+	if mark, err := repository.HighWaterMark(); err != nil || mark != 10 {
+		t.Error(mark, err)
+	}
+}
+
+// A Database that is not a WriteDatabase.
+type testReadDatabase struct {
+	gobdb.Database
+}
+
+func TestClientReadOnly(t *testing.T) {
+
+	client, stop := testServe(t, testReadDatabase{gobdb.NewDefaultDatabase(&testRoot{3}, 0, nil)})
+	defer stop()
+
+	if value := client.Read(&testReader{}); value != 3 {
+		t.Error(value)
+	}
+	if _, err1, err2 := client.Write(&testWriter{1}); err1 != nil || err2 == nil {
+		t.Error(err1, err2)
+	}
+	if value := client.Read(&testReader{}); value != 3 {
+		t.Error(value)
+	}
+}
+
+// A WriteDatabase whose Write() returns the given errors.
+type testErrorDatabase struct {
+	gobdb.Database
+	err1, err2 error
+}
+
+func (db testErrorDatabase) Write(writer gobdb.Writer) (interface{}, error, error) {
+	return nil, db.err1, db.err2
+}
+
+func TestClientTypedErrors(t *testing.T) {
+
+	for _, errs := range [][2]error{
+		{&gobdb.ErrUnregisteredWriter{Writer: &testWriter{1}, Err: errors.New("test")}, nil},
+		{&gobdb.ErrLatched{Err: errors.New("test")}, nil},
+		{nil, &gobdb.ErrStaleTransactionId{Id: 1, HighWaterMark: 2}},
+		{&gobdbraft.ErrNotLeader{Leader: "a"}, nil},
+		{nil, errors.New("test")},
+	} {
+		client, stop := testServe(t, testErrorDatabase{nil, errs[0], errs[1]})
+		_, err1, err2 := client.Write(&testWriter{1})
+		stop()
+		for i, err := range []error{err1, err2} {
+			if (err == nil) != (errs[i] == nil) || err != nil && err.Error() != errs[i].Error() {
+				t.Error(err, errs[i])
+			}
+		}
+		switch errs[0].(type) {
+		case *gobdb.ErrUnregisteredWriter:
+			if e, ok := err1.(*gobdb.ErrUnregisteredWriter); !ok || e.Writer.(*testWriter).Increment != 1 {
+				t.Error(err1)
+			} else if _, ok := e.Err.(*RemoteError); !ok {
+				t.Error(e.Err)
+			}
+		case *gobdb.ErrLatched:
+			if _, ok := err1.(*gobdb.ErrLatched); !ok {
+				t.Error(err1)
+			}
+		case *gobdbraft.ErrNotLeader:
+			if e, ok := err1.(*gobdbraft.ErrNotLeader); !ok || e.Leader != "a" {
+				t.Error(err1)
+			}
+		}
+		switch errs[1].(type) {
+		case *gobdb.ErrStaleTransactionId:
+			if e, ok := err2.(*gobdb.ErrStaleTransactionId); !ok || e.Id != 1 || e.HighWaterMark != 2 {
+				t.Error(err2)
+			}
+		case error:
+			if _, ok := err2.(*RemoteError); !ok {
+				t.Error(err2)
+			}
+		}
+	}
+}
+
+func TestClientReadError(t *testing.T) {
+
+	client, stop := testServe(t, gobdb.NewDefaultDatabase(&testRoot{3}, 0, nil))
+	stop()
+
+	if value, err := client.TryRead(&testReader{}); value != nil || err == nil {
+		t.Error(value, err)
+	}
+	if value, ok := client.Read(&testReader{}).(*ErrCall); !ok || value.Err == nil {
+		t.Error(value)
+	}
+}
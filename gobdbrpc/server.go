@@ -0,0 +1,128 @@
+// Package gobdbrpc exposes a gobdb Database over net/rpc, and implements a
+// Database and WriteDatabase that calls it.
+//
+// The Readers, Writers and the values they return are gob encoded, so their
+// types must be registered in both ends, with gob.Register(), and the Writers
+// with gobdb.RegisterWriter().
+//
+// The errors of the Writes keep their types if they are registered with
+// gob.Register() too, like the ones of gobdb and gobdbraft. Otherwise they are sent as a
+// *RemoteError.
+package gobdbrpc
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"net"
+	"net/rpc"
+	"sync"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+// The name of the net/rpc service.
+const ServiceName = "Gobdb"
+
+// The arguments of Server.Read().
+type ReadArgs struct {
+	Reader gobdb.Reader
+}
+
+// The reply of Server.Read().
+type ReadReply struct {
+	Value interface{}
+}
+
+// The arguments of Server.Write().
+type WriteArgs struct {
+	Writer gobdb.Writer
+}
+
+// The reply of Server.Write().
+type WriteReply struct {
+	Value      interface{}
+	Err1, Err2 error
+}
+
+// The error sent by the Server instead of one that is not gob encodable.
+type RemoteError struct {
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return e.Message
+}
+
+func init() {
+	gob.Register(&RemoteError{})
+	gob.Register(&gobdb.ErrUnregisteredWriter{})
+	gob.Register(&gobdb.ErrLatched{})
+	gob.Register(&gobdb.ErrStaleTransactionId{})
+	gob.Register(&gobdb.ErrLocked{})
+}
+
+// The net/rpc service of a Database. The Reads run in parallel, but not with
+// the Writes.
+type Server struct {
+	mutex    sync.RWMutex
+	database gobdb.Database
+}
+
+// New instance. If the Database is not a WriteDatabase, Write() fails.
+func NewServer(database gobdb.Database) *Server {
+	return &Server{database: database}
+}
+
+// It applies the Reader to the Database.
+func (s *Server) Read(args *ReadArgs, reply *ReadReply) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	reply.Value = s.database.Read(args.Reader)
+	return nil
+}
+
+// It applies the Writer to the Database.
+func (s *Server) Write(args *WriteArgs, reply *WriteReply) error {
+	database, ok := s.database.(gobdb.WriteDatabase)
+	if !ok {
+		return errors.New("gobdb: Write() on a Database that is not a WriteDatabase")
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	value, err1, err2 := database.Write(args.Writer)
+	reply.Value, reply.Err1, reply.Err2 = value, encodableError(err1), encodableError(err2)
+	return nil
+}
+
+// It registers the Server in the net/rpc server as ServiceName.
+func (s *Server) Register(server *rpc.Server) error {
+	return server.RegisterName(ServiceName, s)
+}
+
+// It serves the connections of the Listener until it fails.
+func (s *Server) Serve(listener net.Listener) error {
+	server := rpc.NewServer()
+	if err := s.Register(server); err != nil {
+		return err
+	}
+	server.Accept(listener)
+	return nil
+}
+
+// It returns the error if it is gob encodable, with the errors it wraps
+// replaced in the same way, or a *RemoteError with its message.
+func encodableError(err error) error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *gobdb.ErrUnregisteredWriter:
+		err = &gobdb.ErrUnregisteredWriter{Writer: e.Writer, Err: encodableError(e.Err)}
+	case *gobdb.ErrLatched:
+		err = &gobdb.ErrLatched{Err: encodableError(e.Err)}
+	}
+	if gob.NewEncoder(&bytes.Buffer{}).Encode(&err) != nil {
+		return &RemoteError{err.Error()}
+	}
+	return err
+}
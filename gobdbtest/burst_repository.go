@@ -0,0 +1,250 @@
+package gobdbtest
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+// The implementations checked by TestBurstRepository().
+type BurstRepository interface {
+	gobdb.BurstRepository
+	gobdb.WriteBurstRepository
+}
+
+// It checks the implementation with several subtests. The factory must return
+// a new empty repository on every call.
+func TestBurstRepository(t *testing.T, factory func(*testing.T) BurstRepository) {
+	t.Run("Empty", func(t *testing.T) { testBurstRepositoryEmpty(t, factory(t)) })
+	t.Run("Bursts", func(t *testing.T) { testBurstRepositoryBursts(t, factory(t)) })
+	t.Run("Ordering", func(t *testing.T) { testBurstRepositoryOrdering(t, factory(t)) })
+	t.Run("ClosedWriter", func(t *testing.T) { testBurstRepositoryClosedWriter(t, factory(t)) })
+	t.Run("Readers", func(t *testing.T) { testBurstRepositoryReaders(t, factory(t)) })
+	t.Run("HighWaterMark", func(t *testing.T) { testBurstRepositoryHighWaterMark(t, factory(t)) })
+	t.Run("Concurrent", func(t *testing.T) { testBurstRepositoryConcurrent(t, factory(t)) })
+}
+
+// It writes a Burst. It may be invoked from any goroutine.
+func writeBurst(t *testing.T, repository gobdb.WriteBurstRepository, ids ...gobdb.TransactionId) {
+	wburst, err := repository.WriteBurst()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, id := range ids {
+		if err := wburst.Write(gobdb.Transaction{Id: id, Writer: &testWriter{int(id)}}); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := wburst.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+// It reads the whole Burst and returns its TransactionIds.
+func readBurst(t *testing.T, id gobdb.BurstId) []gobdb.TransactionId {
+	reader, err := id.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	ids := []gobdb.TransactionId{}
+	for {
+		transaction, err := reader.Read()
+		if err == io.EOF {
+			return ids
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w, ok := transaction.Writer.(*testWriter); !ok || w.Value != int(transaction.Id) {
+			t.Errorf("%#v", transaction)
+		}
+		ids = append(ids, transaction.Id)
+	}
+}
+
+func listBursts(t *testing.T, repository gobdb.BurstRepository) []gobdb.BurstId {
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gobdb.SortBursts(bursts)
+	return bursts
+}
+
+func testBurstRepositoryEmpty(t *testing.T, repository BurstRepository) {
+	if bursts := listBursts(t, repository); len(bursts) != 0 {
+		t.Error(bursts)
+	}
+	wburst, err := repository.WriteBurst()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wburst.First() != 0 || wburst.Last() != 0 {
+		t.Error(wburst.First(), wburst.Last())
+	}
+	if err := wburst.Close(); err != nil {
+		t.Error(err)
+	}
+	if bursts := listBursts(t, repository); len(bursts) != 0 {
+		t.Error("an empty Burst is listed", bursts)
+	}
+}
+
+func testBurstRepositoryBursts(t *testing.T, repository BurstRepository) {
+	writeBurst(t, repository, 3, 4)
+	writeBurst(t, repository, 1, 2)
+	bursts := listBursts(t, repository)
+	if len(bursts) != 2 {
+		t.Fatal(bursts)
+	}
+	for i, id := range bursts {
+		if id.First() != gobdb.TransactionId(2*i+1) || id.Last() != gobdb.TransactionId(2*i+2) {
+			t.Error(id.First(), id.Last())
+		}
+		if id.Repository() != repository {
+			t.Error(id.Repository())
+		}
+		if ids := readBurst(t, id); len(ids) != 2 || ids[0] != id.First() || ids[1] != id.Last() {
+			t.Error(ids)
+		}
+	}
+}
+
+func testBurstRepositoryOrdering(t *testing.T, repository BurstRepository) {
+	wburst, err := repository.WriteBurst()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []gobdb.TransactionId{2, 5, 9} {
+		if err := wburst.Write(gobdb.Transaction{Id: id, Writer: &testWriter{int(id)}}); err != nil {
+			t.Error(err)
+		}
+	}
+	if wburst.First() != 2 || wburst.Last() != 9 {
+		t.Error(wburst.First(), wburst.Last())
+	}
+	for _, id := range []gobdb.TransactionId{9, 3} {
+		if err := wburst.Write(gobdb.Transaction{Id: id, Writer: &testWriter{int(id)}}); err == nil {
+			t.Error("a Transaction out of order is written", id)
+		}
+	}
+	if err := wburst.Close(); err != nil {
+		t.Error(err)
+	}
+	bursts := listBursts(t, repository)
+	if len(bursts) != 1 {
+		t.Fatal(bursts)
+	}
+	if ids := readBurst(t, bursts[0]); len(ids) != 3 || ids[0] != 2 || ids[1] != 5 || ids[2] != 9 {
+		t.Error(ids)
+	}
+}
+
+func testBurstRepositoryClosedWriter(t *testing.T, repository BurstRepository) {
+	for _, ids := range [][]gobdb.TransactionId{{}, {1}} {
+		wburst, err := repository.WriteBurst()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range ids {
+			if err := wburst.Write(gobdb.Transaction{Id: id, Writer: &testWriter{int(id)}}); err != nil {
+				t.Error(err)
+			}
+		}
+		if err := wburst.Close(); err != nil {
+			t.Error(err)
+		}
+		if err := wburst.Write(gobdb.Transaction{Id: 2, Writer: &testWriter{2}}); err == nil {
+			t.Error("write() on closed BurstWriter", ids)
+		}
+		if err := wburst.Close(); err == nil {
+			t.Error("close() on closed BurstWriter", ids)
+		}
+	}
+	if bursts := listBursts(t, repository); len(bursts) != 1 || bursts[0].Last() != 1 {
+		t.Error(bursts)
+	}
+}
+
+func testBurstRepositoryReaders(t *testing.T, repository BurstRepository) {
+	writeBurst(t, repository, 1, 2, 3)
+	bursts := listBursts(t, repository)
+	if len(bursts) != 1 {
+		t.Fatal(bursts)
+	}
+	reader1, err := bursts[0].Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader1.Close()
+	reader2, err := bursts[0].Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader2.Close()
+	if reader1.Id() != bursts[0] || reader2.Id() != bursts[0] {
+		t.Error(reader1.Id(), reader2.Id())
+	}
+	for _, reader := range []gobdb.BurstReader{reader1, reader2, reader1, reader2} {
+		if _, err := reader.Read(); err != nil {
+			t.Error(err)
+		}
+	}
+	if transaction, err := reader2.Read(); err != nil || transaction.Id != 3 {
+		t.Error(transaction, err)
+	}
+	if _, err := reader2.Read(); err != io.EOF {
+		t.Error(err)
+	}
+	if transaction, err := reader1.Read(); err != nil || transaction.Id != 3 {
+		t.Error(transaction, err)
+	}
+}
+
+func testBurstRepositoryHighWaterMark(t *testing.T, repository BurstRepository) {
+	marker, ok := repository.(gobdb.HighWaterMarker)
+	if !ok {
+		t.Skip("it does not implement gobdb.HighWaterMarker")
+	}
+	if mark, err := marker.HighWaterMark(); err != nil || mark != 0 {
+		t.Error(mark, err)
+	}
+	writeBurst(t, repository, 4, 7)
+	writeBurst(t, repository, 1, 2)
+	if mark, err := marker.HighWaterMark(); err != nil || mark != 7 {
+		t.Error(mark, err)
+	}
+}
+
+func testBurstRepositoryConcurrent(t *testing.T, repository BurstRepository) {
+	const n = 8
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			first := gobdb.TransactionId(10*i + 1)
+			writeBurst(t, repository, first, first+1)
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := repository.Bursts(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	bursts := listBursts(t, repository)
+	if len(bursts) != n {
+		t.Fatal(bursts)
+	}
+	for i, id := range bursts {
+		if id.First() != gobdb.TransactionId(10*i+1) {
+			t.Error(id.First())
+		}
+	}
+}
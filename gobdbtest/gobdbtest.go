@@ -0,0 +1,26 @@
+// Package gobdbtest checks that implementations of the gobdb repositories
+// follow the contracts of their interfaces.
+//
+//	func TestMyBurstRepository(t *testing.T) {
+//		gobdbtest.TestBurstRepository(t, func(t *testing.T) gobdbtest.BurstRepository {
+//			return NewMyBurstRepository()
+//		})
+//	}
+package gobdbtest
+
+import (
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+// The Writer of the Transactions and Snapshots written by the tests.
+type testWriter struct {
+	Value int
+}
+
+func (op *testWriter) Write(root gobdb.Root) (interface{}, error) {
+	return op.Value, nil
+}
+
+func init() {
+	gobdb.RegisterWriter(&testWriter{})
+}
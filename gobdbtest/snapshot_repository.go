@@ -0,0 +1,198 @@
+package gobdbtest
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+// The implementations checked by TestSnapshotRepository().
+type SnapshotRepository interface {
+	gobdb.SnapshotRepository
+	gobdb.WriteSnapshotRepository
+}
+
+// It checks the implementation with several subtests. The factory must return
+// a new empty repository on every call.
+func TestSnapshotRepository(t *testing.T, factory func(*testing.T) SnapshotRepository) {
+	t.Run("Empty", func(t *testing.T) { testSnapshotRepositoryEmpty(t, factory(t)) })
+	t.Run("Snapshots", func(t *testing.T) { testSnapshotRepositorySnapshots(t, factory(t)) })
+	t.Run("ClosedWriter", func(t *testing.T) { testSnapshotRepositoryClosedWriter(t, factory(t)) })
+	t.Run("Readers", func(t *testing.T) { testSnapshotRepositoryReaders(t, factory(t)) })
+	t.Run("Concurrent", func(t *testing.T) { testSnapshotRepositoryConcurrent(t, factory(t)) })
+}
+
+// It writes a Snapshot whose Writers have the given values. It may be invoked
+// from any goroutine.
+func writeSnapshot(t *testing.T, repository gobdb.WriteSnapshotRepository, id gobdb.TransactionId, values ...int) {
+	wsnapshot, err := repository.WriteSnapshot(id)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if wsnapshot.Id() != id {
+		t.Error(wsnapshot.Id())
+	}
+	for _, value := range values {
+		if err := wsnapshot.Write(&testWriter{value}); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := wsnapshot.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+// It reads the whole Snapshot and returns the values of its Writers.
+func readSnapshot(t *testing.T, id gobdb.SnapshotId) []int {
+	reader, err := id.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	values := []int{}
+	for {
+		writer, err := reader.Read()
+		if err == io.EOF {
+			return values
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, ok := writer.(*testWriter)
+		if !ok {
+			t.Fatalf("%#v", writer)
+		}
+		values = append(values, w.Value)
+	}
+}
+
+func listSnapshots(t *testing.T, repository gobdb.SnapshotRepository) []gobdb.SnapshotId {
+	snapshots, err := repository.Snapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gobdb.SortSnapshots(snapshots)
+	return snapshots
+}
+
+func testSnapshotRepositoryEmpty(t *testing.T, repository SnapshotRepository) {
+	if snapshots := listSnapshots(t, repository); len(snapshots) != 0 {
+		t.Error(snapshots)
+	}
+}
+
+func testSnapshotRepositorySnapshots(t *testing.T, repository SnapshotRepository) {
+	writeSnapshot(t, repository, 3, 1, 2, 3)
+	writeSnapshot(t, repository, 7, 4, 5)
+	snapshots := listSnapshots(t, repository)
+	if len(snapshots) != 2 {
+		t.Fatal(snapshots)
+	}
+	// SortSnapshots() puts the newest first
+	if snapshots[0].Id() != 7 || snapshots[1].Id() != 3 {
+		t.Error(snapshots[0].Id(), snapshots[1].Id())
+	}
+	for _, id := range snapshots {
+		if id.Repository() != repository {
+			t.Error(id.Repository())
+		}
+	}
+	if values := readSnapshot(t, snapshots[0]); len(values) != 2 || values[0] != 4 || values[1] != 5 {
+		t.Error(values)
+	}
+	if values := readSnapshot(t, snapshots[1]); len(values) != 3 || values[0] != 1 || values[2] != 3 {
+		t.Error(values)
+	}
+}
+
+func testSnapshotRepositoryClosedWriter(t *testing.T, repository SnapshotRepository) {
+	wsnapshot, err := repository.WriteSnapshot(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wsnapshot.Write(&testWriter{1}); err != nil {
+		t.Error(err)
+	}
+	if snapshots := listSnapshots(t, repository); len(snapshots) != 0 {
+		t.Error("a Snapshot is listed before it is closed", snapshots)
+	}
+	if err := wsnapshot.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := wsnapshot.Write(&testWriter{2}); err == nil {
+		t.Error("write() on closed SnapshotWriter")
+	}
+	if err := wsnapshot.Close(); err == nil {
+		t.Error("close() on closed SnapshotWriter")
+	}
+	snapshots := listSnapshots(t, repository)
+	if len(snapshots) != 1 {
+		t.Fatal(snapshots)
+	}
+	if values := readSnapshot(t, snapshots[0]); len(values) != 1 || values[0] != 1 {
+		t.Error(values)
+	}
+}
+
+func testSnapshotRepositoryReaders(t *testing.T, repository SnapshotRepository) {
+	writeSnapshot(t, repository, 2, 1, 2)
+	snapshots := listSnapshots(t, repository)
+	if len(snapshots) != 1 {
+		t.Fatal(snapshots)
+	}
+	reader1, err := snapshots[0].Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader1.Close()
+	reader2, err := snapshots[0].Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader2.Close()
+	if reader1.Id() != snapshots[0] || reader2.Id() != snapshots[0] {
+		t.Error(reader1.Id(), reader2.Id())
+	}
+	for _, reader := range []gobdb.SnapshotReader{reader1, reader2, reader2} {
+		if _, err := reader.Read(); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := reader2.Read(); err != io.EOF {
+		t.Error(err)
+	}
+	if writer, err := reader1.Read(); err != nil || writer.(*testWriter).Value != 2 {
+		t.Error(writer, err)
+	}
+}
+
+func testSnapshotRepositoryConcurrent(t *testing.T, repository SnapshotRepository) {
+	const n = 8
+	var wg sync.WaitGroup
+	for i := 1; i <= n; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			writeSnapshot(t, repository, gobdb.TransactionId(i), i)
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := repository.Snapshots(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	snapshots := listSnapshots(t, repository)
+	if len(snapshots) != n {
+		t.Fatal(snapshots)
+	}
+	for i, id := range snapshots {
+		if id.Id() != gobdb.TransactionId(n-i) {
+			t.Error(id.Id())
+		}
+	}
+}
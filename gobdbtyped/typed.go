@@ -0,0 +1,125 @@
+//go:build go1.18
+// +build go1.18
+
+// Package gobdbtyped is a type-safe layer on top of the gobdb interfaces, with
+// the type of the Root and of the results of the operations as parameters.
+//
+// The Bursts and Snapshots store the Writers themselves, so they keep the same
+// format and can be recovered with gobdb.ApplyBursts() and
+// gobdb.ApplySnapshot(). That requires every Writer to implement gobdb.Writer
+// too, which it does with ApplyRoot():
+//
+//	func (op *Increment) Apply(root *Counter) (int, error) {
+//		root.Value += op.N
+//		return root.Value, nil
+//	}
+//
+//	func (op *Increment) Write(root gobdb.Root) (interface{}, error) {
+//		return gobdbtyped.ApplyRoot[*Counter, int](op, root)
+//	}
+package gobdbtyped
+
+import (
+	"errors"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+// An operation that reads a T from a Root of type R. It must be deterministic.
+type Reader[R, T any] interface {
+	Read(R) T
+}
+
+// An operation that updates a Root of type R and returns a T. It must be
+// deterministic and gob encodable, see gobdb.RegisterWriter().
+type Writer[R, T any] interface {
+	gobdb.Writer
+	Apply(R) (T, error)
+}
+
+// The function to take a Snapshot of a Root of type R, see gobdb.Snapshooter.
+type Snapshooter[R any] func(R, func(...gobdb.Writer) error) error
+
+// It implements gobdb.Writer.Write() with Writer.Apply().
+func ApplyRoot[R, T any](writer Writer[R, T], root gobdb.Root) (interface{}, error) {
+	return writer.Apply(root.(R))
+}
+
+// A gobdb.Database whose Root is of type R.
+type Database[R any] struct {
+	database gobdb.Database
+}
+
+// New instance. Its Reads require a local gobdb.Database, because the Readers
+// are wrapped; a gobdb.ShardFunc gets them back with Unwrap().
+func NewDatabase[R any](database gobdb.Database) *Database[R] {
+	return &Database[R]{database}
+}
+
+// The gobdb.Database.
+func (db *Database[R]) Untyped() gobdb.Database {
+	return db.database
+}
+
+type reader[R, T any] struct {
+	reader Reader[R, T]
+}
+
+func (r reader[R, T]) Read(root gobdb.Root) interface{} {
+	return r.reader.Read(root.(R))
+}
+
+func (r reader[R, T]) unwrap() interface{} {
+	return r.reader
+}
+
+// It returns the Reader of a gobdb.Reader that wraps it, or the same value
+// otherwise, so that a gobdb.ShardFunc sees the Readers and Writers of Read()
+// and Write() as they were given.
+func Unwrap(op interface{}) interface{} {
+	if r, ok := op.(interface{ unwrap() interface{} }); ok {
+		return r.unwrap()
+	}
+	return op
+}
+
+// It applies the Reader to the Root, see gobdb.Database.Read(). The result is
+// the zero value of T if the Reader returns nil.
+func Read[R, T any](db *Database[R], r Reader[R, T]) (result T) {
+	if value := db.database.Read(reader[R, T]{r}); value != nil {
+		result = value.(T)
+	}
+	return
+}
+
+// It applies the Writer to the Root, see gobdb.WriteDatabase.Write(). The
+// gobdb.Database must be a gobdb.WriteDatabase. The result is the zero value
+// of T if the Writer fails.
+func Write[R, T any](db *Database[R], writer Writer[R, T]) (result T, err1 error, err2 error) {
+	database, ok := db.database.(gobdb.WriteDatabase)
+	if !ok {
+		return result, errors.New("gobdb: Write() on a Database that is not a WriteDatabase"), nil
+	}
+	value, err1, err2 := database.Write(writer)
+	if value != nil {
+		result = value.(T)
+	}
+	return result, err1, err2
+}
+
+// It takes a Snapshot, see gobdb.SnapshotDatabase.TakeSnapshot(). The
+// gobdb.Database must be a gobdb.SnapshotDatabase.
+func (db *Database[R]) TakeSnapshot(snapshooter Snapshooter[R], repository gobdb.WriteSnapshotRepository) error {
+	database, ok := db.database.(gobdb.SnapshotDatabase)
+	if !ok {
+		return errors.New("gobdb: TakeSnapshot() on a Database that is not a SnapshotDatabase")
+	}
+	return database.TakeSnapshot(snapshooter.Untyped(), repository)
+}
+
+// The gobdb.Snapshooter.
+func (s Snapshooter[R]) Untyped() gobdb.Snapshooter {
+	return func(root gobdb.Root, write func(...gobdb.Writer) error) error {
+		return s(root.(R), write)
+	}
+}
@@ -0,0 +1,146 @@
+//go:build go1.18
+// +build go1.18
+
+package gobdbtyped
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/daniel-fanjul-alcuten/gobdb"
+)
+
+type testCounter struct {
+	value int
+}
+
+type testIncrement struct {
+	N int
+}
+
+func (op *testIncrement) Apply(root *testCounter) (int, error) {
+	if op.N < 0 {
+		return 0, errors.New("negative increment")
+	}
+	root.value += op.N
+	return root.value, nil
+}
+
+func (op *testIncrement) Write(root gobdb.Root) (interface{}, error) {
+	return ApplyRoot[*testCounter, int](op, root)
+}
+
+type testGet struct {
+	Plus int
+}
+
+func (op *testGet) Read(root *testCounter) int {
+	return root.value + op.Plus
+}
+
+func testSnapshooter(root *testCounter, write func(...gobdb.Writer) error) error {
+	return write(&testIncrement{root.value})
+}
+
+func init() {
+	gobdb.RegisterWriter(&testIncrement{})
+}
+
+func TestDatabase(t *testing.T) {
+
+	bursts := gobdb.NewMemBurstRepository()
+	snapshots := gobdb.NewMemSnapshotRepository()
+	dispatcher := gobdb.NewDefaultBurstDispatcher(bursts)
+	db := NewDatabase[*testCounter](gobdb.NewDefaultDatabase(&testCounter{}, 0, dispatcher))
+
+	var value int
+	var err1, err2 error
+	if value, err1, err2 = Write[*testCounter, int](db, &testIncrement{3}); value != 3 || err1 != nil || err2 != nil {
+		t.Error(value, err1, err2)
+	}
+	if value, err1, err2 = Write[*testCounter, int](db, &testIncrement{-1}); value != 0 || err1 == nil || err2 != nil {
+		t.Error(value, err1, err2)
+	}
+	if err := db.TakeSnapshot(testSnapshooter, snapshots); err != nil {
+		t.Error(err)
+	}
+	if value, err1, err2 = Write[*testCounter, int](db, &testIncrement{2}); value != 5 || err1 != nil || err2 != nil {
+		t.Error(value, err1, err2)
+	}
+	if value = Read[*testCounter, int](db, &testGet{10}); value != 15 {
+		t.Error(value)
+	}
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+
+	// the untyped API recovers the same state
+	root := &testCounter{}
+	var id gobdb.TransactionId
+	if _, err := gobdb.Recover(context.Background(), root, snapshots, bursts, &id, nil); err != nil {
+		t.Error(err)
+	}
+	if id != 2 || root.value != 5 {
+		t.Error(id, root.value)
+	}
+}
+
+func TestDatabaseReadOnly(t *testing.T) {
+
+	db := NewDatabase[*testCounter](gobdb.NewShardedDatabase(func(interface{}) int { return 0 }))
+	if _, err1, _ := Write[*testCounter, int](db, &testIncrement{1}); err1 == nil {
+		t.Error(err1)
+	}
+	if err := db.TakeSnapshot(testSnapshooter, gobdb.NewMemSnapshotRepository()); err == nil {
+		t.Error(err)
+	}
+}
+
+// A Reader whose result is an interface.
+type testGetError struct {
+}
+
+func (op *testGetError) Read(root *testCounter) error {
+	return nil
+}
+
+func TestReadNilInterface(t *testing.T) {
+
+	db := NewDatabase[*testCounter](gobdb.NewDefaultDatabase(&testCounter{}, 0, nil))
+	if err := Read[*testCounter, error](db, &testGetError{}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUnwrapSharded(t *testing.T) {
+
+	shard := func(op interface{}) int {
+		switch op := Unwrap(op).(type) {
+		case *testGet:
+			return op.Plus
+		case *testIncrement:
+			return op.N
+		}
+		return -1
+	}
+	roots := []*testCounter{{}, {}}
+	sharded := gobdb.NewShardedDatabase(shard, gobdb.NewDefaultDatabase(roots[0], 0, nil), gobdb.NewDefaultDatabase(roots[1], 0, nil))
+	db := NewDatabase[*testCounter](sharded)
+
+	if value, err1, err2 := Write[*testCounter, int](db, &testIncrement{1}); value != 1 || err1 != nil || err2 != nil {
+		t.Error(value, err1, err2)
+	}
+	if value := Read[*testCounter, int](db, &testGet{1}); value != 2 {
+		t.Error(value)
+	}
+	if value := Read[*testCounter, int](db, &testGet{0}); value != 0 {
+		t.Error(value)
+	}
+	if roots[0].value != 0 || roots[1].value != 1 {
+		t.Error(roots[0].value, roots[1].value)
+	}
+	if op := Unwrap(3); op != 3 {
+		t.Error(op)
+	}
+}
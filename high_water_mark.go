@@ -0,0 +1,75 @@
+package gobdb
+
+import (
+	"fmt"
+	"sync"
+)
+
+// The error returned when a Transaction would reuse a TransactionId that a
+// WriteBurstRepository already has, usually because the Database was created
+// with a stale last TransactionId.
+type ErrStaleTransactionId struct {
+	Id            TransactionId
+	HighWaterMark TransactionId
+}
+
+func (e *ErrStaleTransactionId) Error() string {
+	return fmt.Sprintf("gobdb: TransactionId %d is not above the high-water mark %d", e.Id, e.HighWaterMark)
+}
+
+// An optional interface of WriteBurstRepositories and BurstDispatchers that
+// know their high-water mark.
+type HighWaterMarker interface {
+	// The highest TransactionId written, including the Bursts still being
+	// written, zero if there are none or it is unknown.
+	HighWaterMark() (TransactionId, error)
+}
+
+// The highest TransactionId written by the BurstWriters of a repository, and
+// whether the Bursts already in the repository have been included.
+// Thread-safe.
+type highWaterMark struct {
+	mutex  sync.Mutex
+	id     TransactionId
+	listed bool
+}
+
+func (m *highWaterMark) get() TransactionId {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.id
+}
+
+func (m *highWaterMark) update(id TransactionId) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if id > m.id {
+		m.id = id
+	}
+}
+
+// It returns the id if the Bursts have been included since the last forget().
+func (m *highWaterMark) getListed() (TransactionId, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.id, m.listed
+}
+
+// It includes the Bursts of the repository.
+func (m *highWaterMark) updateListed(id TransactionId) TransactionId {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if id > m.id {
+		m.id = id
+	}
+	m.listed = true
+	return m.id
+}
+
+// It forgets that the Bursts have been included, because others may write
+// them.
+func (m *highWaterMark) forget() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.listed = false
+}
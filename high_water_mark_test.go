@@ -0,0 +1,167 @@
+package gobdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBurstDispatcherHighWaterMarkerInterface(t *testing.T) {
+
+	var i interface{} = NewDefaultBurstDispatcher(nil)
+	if _, ok := i.(HighWaterMarker); !ok {
+		t.Error(i)
+	}
+	i = NewNumTransactionsBurstDispatcher(1, nil)
+	if _, ok := i.(HighWaterMarker); !ok {
+		t.Error(i)
+	}
+}
+
+func testHighWaterMark(t *testing.T, repository interface {
+	WriteBurstRepository
+	HighWaterMarker
+}) {
+
+	if mark, err := repository.HighWaterMark(); err != nil || mark != 0 {
+		t.Error(mark, err)
+	}
+	testWriteBurst(t, repository, 1, 2)
+
+	wburst, err := repository.WriteBurst()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wburst.Write(Transaction{3, &testWriter{3}}); err != nil {
+		t.Error(err)
+	}
+	if mark, err := repository.HighWaterMark(); err != nil || mark != 3 {
+		t.Error(mark, err)
+	}
+	if err := wburst.Close(); err != nil {
+		t.Error(err)
+	}
+	if mark, err := repository.HighWaterMark(); err != nil || mark != 3 {
+		t.Error(mark, err)
+	}
+}
+
+func TestMemBurstRepositoryHighWaterMark(t *testing.T) {
+	testHighWaterMark(t, NewMemBurstRepository())
+}
+
+func TestDirBurstRepositoryHighWaterMark(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testHighWaterMark(t, NewDirBurstRepository(dir))
+	if mark, err := NewDirBurstRepository(dir).HighWaterMark(); err != nil || mark != 3 {
+		t.Error(mark, err)
+	}
+}
+
+func TestDefaultDatabaseStaleLastId(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 2, 3)
+
+	root := &testRoot{}
+	database := NewDefaultDatabase(root, 2, NewDefaultBurstDispatcher(repository))
+	_, err1, err2 := database.Write(&testWriter{1})
+	if stale, ok := err1.(*ErrStaleTransactionId); !ok || stale.Id != 3 || stale.HighWaterMark != 3 {
+		t.Error(err1)
+	}
+	if err2 != nil {
+		t.Error(err2)
+	}
+	if root.counter != 0 {
+		t.Error(root.counter)
+	}
+
+	database = NewDefaultDatabase(root, 3, NewDefaultBurstDispatcher(repository))
+	if _, err1, err2 := database.Write(&testWriter{1}); err1 != nil || err2 != nil {
+		t.Error(err1, err2)
+	}
+	if mark, err := repository.HighWaterMark(); err != nil || mark != 4 {
+		t.Error(mark, err)
+	}
+}
+
+func TestDefaultBurstDispatcherStaleTransactionId(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 2)
+
+	dispatcher := NewDefaultBurstDispatcher(repository)
+	if err := dispatcher.Write(Transaction{2, &testWriter{2}}); err == nil {
+		t.Error(err)
+	} else if _, ok := err.(*ErrStaleTransactionId); !ok {
+		t.Error(err)
+	}
+	if err := dispatcher.Write(Transaction{3, &testWriter{3}}); err != nil {
+		t.Error(err)
+	}
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDirBurstRepositoryHighWaterMarkCache(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repository := NewDirBurstRepository(dir)
+	testWriteBurst(t, repository, 1, 2)
+	if mark, err := repository.HighWaterMark(); err != nil || mark != 2 {
+		t.Error(mark, err)
+	}
+
+	// the directory is not listed again while the lock is held
+	if err := ioutil.WriteFile(filepath.Join(dir, "burst-3-4.gobdb"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if mark, err := repository.HighWaterMark(); err != nil || mark != 2 {
+		t.Error(mark, err)
+	}
+
+	if err := repository.Unlock(); err != nil {
+		t.Error(err)
+	}
+	if mark, err := repository.HighWaterMark(); err != nil || mark != 4 {
+		t.Error(mark, err)
+	}
+}
+
+// A WriteBurstRepository that does not implement HighWaterMarker.
+type testNoMarkBurstRepository struct {
+	repository *MemBurstRepository
+}
+
+func (r *testNoMarkBurstRepository) WriteBurst() (BurstWriter, error) {
+	return r.repository.WriteBurst()
+}
+
+func TestDefaultBurstDispatcherWithoutHighWaterMark(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 2)
+	dispatcher := NewDefaultBurstDispatcher(&testNoMarkBurstRepository{repository})
+	if mark, err := dispatcher.HighWaterMark(); err != nil || mark != 0 {
+		t.Error(mark, err)
+	}
+	if err := dispatcher.Write(Transaction{3, &testWriter{3}}); err != nil {
+		t.Error(err)
+	}
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,48 @@
+package gobdb
+
+import (
+	"context"
+	"fmt"
+)
+
+// The error returned as the first one by DefaultDatabase.Write(),
+// TakeSnapshot() and TakeDeltaSnapshot() after the BurstDispatcher has failed.
+// The Root may have Transactions that have not been written, so it is read-only
+// until DefaultDatabase.Reopen().
+type ErrLatched struct {
+	Err error
+}
+
+func (e *ErrLatched) Error() string {
+	return fmt.Sprintf("gobdb: read-only after a BurstDispatcher error: %v", e.Err)
+}
+
+// It returns an *ErrLatched if the BurstDispatcher has failed, nil otherwise.
+func (db *DefaultDatabase) Latched() error {
+	if db.latched == nil {
+		return nil
+	}
+	return &ErrLatched{db.latched}
+}
+
+// It recovers a new Root from the repositories with Recover() and replaces the
+// Root, the last TransactionId, the base of the next delta Snapshot and the
+// BurstDispatcher, so that it is writable again. The previous BurstDispatcher
+// should be closed before, so that its Bursts are recovered. If it fails, the
+// DefaultDatabase is not modified.
+func (db *DefaultDatabase) Reopen(ctx context.Context, root Root, snapshots SnapshotRepository, bursts BurstRepository, dispatcher BurstDispatcher) error {
+	var lastId TransactionId
+	plan, err := Recover(ctx, root, snapshots, bursts, &lastId, nil)
+	if err != nil {
+		return err
+	}
+	var snapshotId TransactionId
+	if len(plan.Deltas) > 0 {
+		snapshotId = plan.Deltas[len(plan.Deltas)-1].Id()
+	} else if plan.Snapshot != nil {
+		snapshotId = plan.Snapshot.Id()
+	}
+	db.root, db.lastId, db.dispatcher = root, lastId, dispatcher
+	db.snapshotId, db.checked, db.latched = snapshotId, false, nil
+	return nil
+}
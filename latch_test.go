@@ -0,0 +1,54 @@
+package gobdb
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDefaultDatabaseLatched(t *testing.T) {
+
+	bursts := NewMemBurstRepository()
+	snapshots := NewMemSnapshotRepository()
+	testWriteBurst(t, bursts, 1, 2)
+
+	root := &testRoot{11 + 12}
+	database := NewDefaultDatabase(root, 2, &testFailBurstDispatcher{})
+	if _, err1, err2 := database.Write(&testWriter{100}); err1 != nil || err2 == nil {
+		t.Error(err1, err2)
+	}
+	if err := database.Latched(); err == nil {
+		t.Error(err)
+	}
+
+	if _, err1, err2 := database.Write(&testWriter{1}); err2 != nil {
+		t.Error(err2)
+	} else if _, ok := err1.(*ErrLatched); !ok {
+		t.Error(err1)
+	}
+	if err := database.TakeSnapshot(testSnapshooter, snapshots); err == nil {
+		t.Error(err)
+	}
+	if value := database.Read(&testReader{}); value != 123 {
+		t.Error(value)
+	}
+
+	dispatcher := NewDefaultBurstDispatcher(bursts)
+	if err := database.Reopen(context.Background(), &testRoot{}, snapshots, bursts, dispatcher); err != nil {
+		t.Error(err)
+	}
+	if err := database.Latched(); err != nil {
+		t.Error(err)
+	}
+	if value := database.Read(&testReader{}); value != 23 {
+		t.Error(value)
+	}
+	if value, err1, err2 := database.Write(&testWriter{1}); value != 24 || err1 != nil || err2 != nil {
+		t.Error(value, err1, err2)
+	}
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+	if mark, err := bursts.HighWaterMark(); err != nil || mark != 3 {
+		t.Error(mark, err)
+	}
+}
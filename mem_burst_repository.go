@@ -7,18 +7,20 @@ import (
 	"sync"
 )
 
-// A BurstRepository and WriteBurstRepository that keeps the data in memory.
+// A BurstRepository, WriteBurstRepository and RemoveBurstRepository that keeps
+// the data in memory.
 // Thread-safe, but BurstReaders and BurstWriters are not.
 type MemBurstRepository struct {
-	mutex  sync.Mutex
-	count  int
-	bursts map[TransactionId]map[TransactionId]map[*memBurstId][]byte
+	mutex   sync.Mutex
+	count   int
+	bursts  map[TransactionId]map[TransactionId]map[*memBurstId][]byte
+	written TransactionId
 }
 
 // New instance.
 func NewMemBurstRepository() *MemBurstRepository {
 	bursts := make(map[TransactionId]map[TransactionId]map[*memBurstId][]byte)
-	return &MemBurstRepository{sync.Mutex{}, 0, bursts}
+	return &MemBurstRepository{sync.Mutex{}, 0, bursts, 0}
 }
 
 // Implements BurstRepository.Bursts().
@@ -36,6 +38,21 @@ func (r *MemBurstRepository) Bursts() ([]BurstId, error) {
 	return ids, nil
 }
 
+// Implements HighWaterMarker.HighWaterMark().
+func (r *MemBurstRepository) HighWaterMark() (TransactionId, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	mark := r.written
+	for _, m2 := range r.bursts {
+		for last := range m2 {
+			if last > mark {
+				mark = last
+			}
+		}
+	}
+	return mark, nil
+}
+
 // Implements WriteBurstRepository.WriteBurst().
 func (r *MemBurstRepository) WriteBurst() (BurstWriter, error) {
 	buffer := &bytes.Buffer{}
@@ -43,6 +60,34 @@ func (r *MemBurstRepository) WriteBurst() (BurstWriter, error) {
 	return &memBurstWriter{encoder, buffer, 0, 0, r}, nil
 }
 
+// Implements RemoveBurstRepository.RemoveBurst(). The BurstIds of other
+// repositories, like the wrappers of this one, remove a Burst with the same
+// range, as in DirBurstRepository.
+func (r *MemBurstRepository) RemoveBurst(id BurstId) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	m2 := r.bursts[id.First()]
+	m3 := m2[id.Last()]
+	mid, ok := id.(*memBurstId)
+	if !ok {
+		for mid = range m3 {
+			break
+		}
+	}
+	if _, ok := m3[mid]; !ok {
+		return errors.New("gobdb: BurstId not found on MemBurstRepository")
+	}
+	delete(m3, mid)
+	if len(m3) == 0 {
+		delete(m2, id.Last())
+	}
+	if len(m2) == 0 {
+		delete(r.bursts, id.First())
+	}
+	r.count--
+	return nil
+}
+
 type memBurstId struct {
 	first, last TransactionId
 	repository  *MemBurstRepository
@@ -60,7 +105,7 @@ func (id *memBurstId) Repository() BurstRepository {
 	return id.repository
 }
 
-func (id *memBurstId) Read() (BurstReader, error) {
+func (id *memBurstId) data() ([]byte, error) {
 	id.repository.mutex.Lock()
 	defer id.repository.mutex.Unlock()
 	m2, ok := id.repository.bursts[id.first]
@@ -69,15 +114,30 @@ func (id *memBurstId) Read() (BurstReader, error) {
 		if ok {
 			data, ok := m3[id]
 			if ok {
-				decoder := gob.NewDecoder(bytes.NewReader(data))
-				return &memBurstReader{decoder, id}, nil
+				return data, nil
 			}
 		}
 	}
 	return nil, errors.New("gobdb: BurstId not found on MemBurstRepository")
 }
 
+func (id *memBurstId) Size() (int64, error) {
+	data, err := id.data()
+	return int64(len(data)), err
+}
+
+func (id *memBurstId) Read() (BurstReader, error) {
+	data, err := id.data()
+	if err != nil {
+		return nil, err
+	}
+	counter := &countingReader{bytes.NewReader(data), 0}
+	decoder := gob.NewDecoder(counter)
+	return &memBurstReader{counter, decoder, id}, nil
+}
+
 type memBurstReader struct {
+	counter *countingReader
 	decoder *gob.Decoder
 	mid     *memBurstId
 }
@@ -87,11 +147,24 @@ func (br *memBurstReader) Id() BurstId {
 }
 
 func (br *memBurstReader) Read() (Transaction, error) {
+	transaction, err := br.readRaw()
+	if err == nil {
+		transaction.Writer, err = migrateWriter(transaction.Writer)
+	}
+	return transaction, err
+}
+
+// Implements rawBurstReader.readRaw().
+func (br *memBurstReader) readRaw() (Transaction, error) {
 	var transaction Transaction
 	err := br.decoder.Decode(&transaction)
 	return transaction, err
 }
 
+func (br *memBurstReader) BytesRead() int64 {
+	return br.counter.count
+}
+
 func (br *memBurstReader) Close() error {
 	return nil
 }
@@ -124,6 +197,11 @@ func (bw *memBurstWriter) Write(transaction Transaction) error {
 			bw.first = transaction.Id
 		}
 		bw.last = transaction.Id
+		bw.repository.mutex.Lock()
+		if transaction.Id > bw.repository.written {
+			bw.repository.written = transaction.Id
+		}
+		bw.repository.mutex.Unlock()
 	}
 	return err
 }
@@ -133,6 +211,8 @@ func (bw *memBurstWriter) Close() error {
 		return errors.New("gobdb: close() on closed BurstWriter")
 	}
 	if bw.last == 0 {
+		bw.buffer = nil
+		bw.encoder = nil
 		return nil
 	}
 	bw.repository.mutex.Lock()
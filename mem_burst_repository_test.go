@@ -160,7 +160,7 @@ func TestMemBurstRepositoryBursts(t *testing.T) {
 		t.Fatal(rburst)
 	}
 	if rburst.Id() != id {
-		t.Error(rburst.Id)
+		t.Error(rburst.Id())
 	}
 	defer rburst.Close()
 
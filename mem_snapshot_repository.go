@@ -7,7 +7,7 @@ import (
 	"sync"
 )
 
-// A container of Snapshots that keeps the data in memory.
+// A container of full and delta Snapshots that keeps the data in memory.
 // Thread-safe, but SnapshotReaders and SnapshotWriters are not.
 type MemSnapshotRepository struct {
 	mutex sync.Mutex
@@ -23,26 +23,42 @@ func NewMemSnapshotRepository() *MemSnapshotRepository {
 
 // Implements SnapshotRepository.Snapshots().
 func (r *MemSnapshotRepository) Snapshots() ([]SnapshotId, error) {
+	return r.snapshots(false), nil
+}
+
+// Implements DeltaSnapshotRepository.DeltaSnapshots().
+func (r *MemSnapshotRepository) DeltaSnapshots() ([]SnapshotId, error) {
+	return r.snapshots(true), nil
+}
+
+func (r *MemSnapshotRepository) snapshots(delta bool) []SnapshotId {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 	ids := make([]SnapshotId, 0, r.count)
 	for _, m2 := range r.snaps {
 		for id := range m2 {
-			ids = append(ids, id)
+			if (id.base != 0) == delta {
+				ids = append(ids, id)
+			}
 		}
 	}
-	return ids, nil
+	return ids
 }
 
 // Implements WriteSnapshotRepository.WriteSnapshot().
 func (r *MemSnapshotRepository) WriteSnapshot(id TransactionId) (SnapshotWriter, error) {
+	return r.WriteDeltaSnapshot(0, id)
+}
+
+// Implements WriteDeltaSnapshotRepository.WriteDeltaSnapshot().
+func (r *MemSnapshotRepository) WriteDeltaSnapshot(base, id TransactionId) (SnapshotWriter, error) {
 	buffer := &bytes.Buffer{}
 	encoder := gob.NewEncoder(buffer)
-	return &memSnapshotWriter{encoder, buffer, id, r}, nil
+	return &memSnapshotWriter{encoder, buffer, id, base, r}, nil
 }
 
 type memSnapshotId struct {
-	id         TransactionId
+	id, base   TransactionId
 	repository *MemSnapshotRepository
 }
 
@@ -50,25 +66,44 @@ func (id *memSnapshotId) Id() TransactionId {
 	return id.id
 }
 
+func (id *memSnapshotId) Base() TransactionId {
+	return id.base
+}
+
 func (id *memSnapshotId) Repository() SnapshotRepository {
 	return id.repository
 }
 
-func (id *memSnapshotId) Read() (SnapshotReader, error) {
+func (id *memSnapshotId) data() ([]byte, error) {
 	id.repository.mutex.Lock()
 	defer id.repository.mutex.Unlock()
 	m2, ok := id.repository.snaps[id.id]
 	if ok {
 		data, ok := m2[id]
 		if ok {
-			decoder := gob.NewDecoder(bytes.NewReader(data))
-			return &memSnapshotReader{decoder, id}, nil
+			return data, nil
 		}
 	}
 	return nil, errors.New("gobdb: SnapshotId not found on MemSnapshotRepository")
 }
 
+func (id *memSnapshotId) Size() (int64, error) {
+	data, err := id.data()
+	return int64(len(data)), err
+}
+
+func (id *memSnapshotId) Read() (SnapshotReader, error) {
+	data, err := id.data()
+	if err != nil {
+		return nil, err
+	}
+	counter := &countingReader{bytes.NewReader(data), 0}
+	decoder := gob.NewDecoder(counter)
+	return &memSnapshotReader{counter, decoder, id}, nil
+}
+
 type memSnapshotReader struct {
+	counter *countingReader
 	decoder *gob.Decoder
 	mid     *memSnapshotId
 }
@@ -80,9 +115,16 @@ func (br *memSnapshotReader) Id() SnapshotId {
 func (br *memSnapshotReader) Read() (Writer, error) {
 	var writer Writer
 	err := br.decoder.Decode(&writer)
+	if err == nil {
+		writer, err = migrateWriter(writer)
+	}
 	return writer, err
 }
 
+func (br *memSnapshotReader) BytesRead() int64 {
+	return br.counter.count
+}
+
 func (br *memSnapshotReader) Close() error {
 	return nil
 }
@@ -90,7 +132,7 @@ func (br *memSnapshotReader) Close() error {
 type memSnapshotWriter struct {
 	encoder    *gob.Encoder
 	buffer     *bytes.Buffer
-	id         TransactionId
+	id, base   TransactionId
 	repository *MemSnapshotRepository
 }
 
@@ -117,7 +159,7 @@ func (bw *memSnapshotWriter) Close() error {
 		m2 = make(map[*memSnapshotId][]byte)
 		bw.repository.snaps[bw.id] = m2
 	}
-	mid := &memSnapshotId{bw.id, bw.repository}
+	mid := &memSnapshotId{bw.id, bw.base, bw.repository}
 	m2[mid] = bw.buffer.Bytes()
 	bw.buffer = nil
 	bw.encoder = nil
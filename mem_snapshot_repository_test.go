@@ -45,6 +45,12 @@ func TestMemSnapshotRepositoryInterface(t *testing.T) {
 	if _, ok := i.(WriteSnapshotRepository); !ok {
 		t.Error(i)
 	}
+	if _, ok := i.(DeltaSnapshotRepository); !ok {
+		t.Error(i)
+	}
+	if _, ok := i.(WriteDeltaSnapshotRepository); !ok {
+		t.Error(i)
+	}
 }
 
 func TestMemSnapshotRepositoryEmpty(t *testing.T) {
@@ -112,7 +118,7 @@ func TestMemSnapshotRepositorySnapshots(t *testing.T) {
 		t.Error(err)
 	}
 	if rsnapshot.Id() != id {
-		t.Error(rsnapshot.Id)
+		t.Error(rsnapshot.Id())
 	}
 	defer rsnapshot.Close()
 
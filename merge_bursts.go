@@ -0,0 +1,162 @@
+package gobdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// An optional interface of WriteBurstRepositories that can remove Bursts.
+type RemoveBurstRepository interface {
+	// It removes a Burst listed by the repository.
+	RemoveBurst(BurstId) error
+}
+
+// A repository whose Bursts can be merged by MergeBursts() and
+// CompactBursts().
+type MergeBurstRepository interface {
+	BurstRepository
+	WriteBurstRepository
+	RemoveBurstRepository
+}
+
+// It rewrites several Bursts of the repository as a new one and then removes
+// them. Every Burst must start at most right after the Last() of the previous
+// ones, in the order of SortBursts(), and they are assumed to contain all the
+// Transactions in their ranges, as in PlanBursts(). The Transactions that are
+// already in the new Burst are skipped, and the Writers are migrated when they
+// are read.
+//
+// The new Burst is complete before any other one is removed, so the listed
+// Bursts always contain every Transaction, maybe twice, which ApplyBursts()
+// and PlanBursts() allow. The Bursts are removed one by one, so if a removal
+// fails, the new Burst and the ones not removed yet remain, and a later
+// CompactBursts() removes them because they are contained in the new one.
+// It may run while a BurstDispatcher writes newer
+// Bursts to the repository, but a DirBurstRepository must be the same instance,
+// because WriteBurst() and RemoveBurst() acquire its exclusive lock.
+//
+// If the Context is cancelled or a Burst can not be read, the new Burst keeps
+// the Transactions copied so far and only the Bursts fully copied are removed.
+// It returns the number of Bursts removed.
+func MergeBursts(ctx context.Context, repository MergeBurstRepository, burstIds []BurstId) (int, error) {
+
+	ids := append([]BurstId(nil), burstIds...)
+	SortBursts(ids)
+	if len(ids) < 2 {
+		return 0, nil
+	}
+	last := ids[0].Last()
+	for _, id := range ids[1:] {
+		if id.First() > last+1 {
+			return 0, fmt.Errorf("gobdb: Burst %d-%d does not follow the Transaction %d", id.First(), id.Last(), last)
+		}
+		if id.Last() > last {
+			last = id.Last()
+		}
+	}
+
+	// the first Burst already contains the others
+	if ids[0].Last() == last {
+		return removeBursts(repository, ids[1:], nil)
+	}
+
+	writer, err := repository.WriteBurst()
+	if err != nil {
+		return 0, err
+	}
+	copied := 0
+	for _, id := range ids {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		if err = copyBurst(writer, id); err != nil {
+			break
+		}
+		copied++
+	}
+	if err2 := writer.Close(); err2 != nil {
+		if err == nil {
+			err = err2
+		}
+		return 0, err
+	}
+	removed, err2 := removeBursts(repository, ids[:copied], writer)
+	if err == nil {
+		err = err2
+	}
+	return removed, err
+}
+
+// It appends the Transactions of a Burst after the Last() of the BurstWriter.
+func copyBurst(writer BurstWriter, id BurstId) error {
+	reader, err := readBurst(id, writer.Last()+1)
+	if err != nil {
+		return err
+	}
+	for {
+		transaction, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err == nil && transaction.Id > writer.Last() {
+			err = writer.Write(transaction)
+		}
+		if err != nil {
+			reader.Close()
+			return err
+		}
+	}
+	return reader.Close()
+}
+
+// It removes the Bursts, but not the ones with the range of the new Burst,
+// that may have replaced them.
+func removeBursts(repository RemoveBurstRepository, ids []BurstId, merged BurstWriter) (int, error) {
+	removed := 0
+	for _, id := range ids {
+		if merged != nil && id.First() == merged.First() && id.Last() == merged.Last() {
+			continue
+		}
+		if err := repository.RemoveBurst(id); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// It merges the runs of Bursts of the repository with MergeBursts(), so that
+// every new Burst is not larger than the given number of bytes, given by the
+// BurstIds that implement Sizer. Zero means no limit. The Bursts contained in
+// others are removed too, so it also cleans up after an interrupted merge.
+// It returns the number of Bursts removed.
+func CompactBursts(ctx context.Context, repository MergeBurstRepository, maxBytes int64) (int, error) {
+
+	ids, err := repository.Bursts()
+	if err != nil {
+		return 0, err
+	}
+	SortBursts(ids)
+
+	removed := 0
+	for start := 0; start < len(ids); {
+		last, size := ids[start].Last(), sizeOf(ids[start])
+		end := start + 1
+		for ; end < len(ids) && ids[end].First() <= last+1; end++ {
+			if ids[end].Last() > last {
+				if maxBytes > 0 && size+sizeOf(ids[end]) > maxBytes {
+					break
+				}
+				last, size = ids[end].Last(), size+sizeOf(ids[end])
+			}
+		}
+		n, err := MergeBursts(ctx, repository, ids[start:end])
+		removed += n
+		if err != nil {
+			return removed, err
+		}
+		start = end
+	}
+	return removed, nil
+}
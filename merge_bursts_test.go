@@ -0,0 +1,301 @@
+package gobdb
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeBurstsInterface(t *testing.T) {
+
+	var i interface{} = NewMemBurstRepository()
+	if _, ok := i.(MergeBurstRepository); !ok {
+		t.Error(i)
+	}
+	i = NewDirBurstRepository("")
+	if _, ok := i.(MergeBurstRepository); !ok {
+		t.Error(i)
+	}
+}
+
+// It returns the ranges of the Bursts of the repository, sorted.
+func testBurstRanges(t *testing.T, repository BurstRepository) [][2]TransactionId {
+	ids, err := repository.Bursts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	SortBursts(ids)
+	ranges := [][2]TransactionId{}
+	for _, id := range ids {
+		ranges = append(ranges, [2]TransactionId{id.First(), id.Last()})
+	}
+	return ranges
+}
+
+// It applies the Bursts of the repository and checks the result.
+func testCheckBursts(t *testing.T, repository BurstRepository, lastId TransactionId, counter int) {
+	ids, err := repository.Bursts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := &testRoot{0}
+	var id TransactionId
+	if err := ApplyBursts(root, 0, &id, ids); err != nil {
+		t.Error(err)
+	}
+	if id != lastId {
+		t.Error(id)
+	}
+	if root.counter != counter {
+		t.Error(root.counter)
+	}
+}
+
+func TestCompactBursts(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 2)
+	testWriteBurst(t, repository, 3)
+	testWriteBurst(t, repository, 4, 5)
+	testWriteBurst(t, repository, 7)
+	testWriteBurst(t, repository, 8)
+
+	removed, err := CompactBursts(context.Background(), repository, 0)
+	if err != nil {
+		t.Error(err)
+	}
+	if removed != 5 {
+		t.Error(removed)
+	}
+	ranges := testBurstRanges(t, repository)
+	if len(ranges) != 2 || ranges[0] != [2]TransactionId{1, 5} || ranges[1] != [2]TransactionId{7, 8} {
+		t.Error(ranges)
+	}
+	testCheckBursts(t, repository, 5, 11+12+13+14+15)
+
+	ids, _ := repository.Bursts()
+	if _, err := MergeBursts(context.Background(), repository, ids); err == nil {
+		t.Error(err)
+	}
+}
+
+func TestCompactBurstsOverlap(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1, 2, 3)
+	testWriteBurst(t, repository, 2, 3, 4)
+	testWriteBurst(t, repository, 2, 3)
+	testWriteBurst(t, repository, 5)
+
+	removed, err := CompactBursts(context.Background(), repository, 0)
+	if err != nil {
+		t.Error(err)
+	}
+	if removed != 4 {
+		t.Error(removed)
+	}
+	ranges := testBurstRanges(t, repository)
+	if len(ranges) != 1 || ranges[0] != [2]TransactionId{1, 5} {
+		t.Error(ranges)
+	}
+	testCheckBursts(t, repository, 5, 11+12+13+14+15)
+
+	// a Burst that contains the others is kept
+	testWriteBurst(t, repository, 2, 3)
+	testWriteBurst(t, repository, 1, 2, 3, 4, 5)
+	if removed, err = CompactBursts(context.Background(), repository, 0); removed != 2 || err != nil {
+		t.Error(removed, err)
+	}
+	ranges = testBurstRanges(t, repository)
+	if len(ranges) != 1 || ranges[0] != [2]TransactionId{1, 5} {
+		t.Error(ranges)
+	}
+	testCheckBursts(t, repository, 5, 11+12+13+14+15)
+}
+
+func TestCompactBurstsMaxBytes(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	for id := TransactionId(1); id <= 8; id++ {
+		testWriteBurst(t, repository, id)
+	}
+	ids, _ := repository.Bursts()
+	size := sizeOf(ids[0])
+
+	if _, err := CompactBursts(context.Background(), repository, 2*size); err != nil {
+		t.Error(err)
+	}
+	ranges := testBurstRanges(t, repository)
+	if len(ranges) != 4 || ranges[0] != [2]TransactionId{1, 2} || ranges[3] != [2]TransactionId{7, 8} {
+		t.Error(ranges)
+	}
+	testCheckBursts(t, repository, 8, 11+12+13+14+15+16+17+18)
+}
+
+func TestMergeBurstsCancel(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	testWriteBurst(t, repository, 1)
+	testWriteBurst(t, repository, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ids, _ := repository.Bursts()
+	removed, err := MergeBursts(ctx, repository, ids)
+	if err != context.Canceled {
+		t.Error(err)
+	}
+	if removed != 0 {
+		t.Error(removed)
+	}
+	if ranges := testBurstRanges(t, repository); len(ranges) != 2 {
+		t.Error(ranges)
+	}
+}
+
+func TestCompactBurstsDir(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repository := NewDirBurstRepository(dir)
+	repository.SetIndexInterval(2)
+	dispatcher := NewNumTransactionsBurstDispatcher(1, NewDefaultBurstDispatcher(repository))
+	database := NewDefaultDatabase(&testRoot{0}, 0, dispatcher)
+
+	// the writer keeps appending Bursts while they are merged
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 50; i++ {
+			if _, err1, err2 := database.Write(&testWriter{i}); err1 != nil || err2 != nil {
+				t.Error(err1, err2)
+			}
+		}
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		if _, err := CompactBursts(context.Background(), repository, 0); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := CompactBursts(context.Background(), repository, 0); err != nil {
+		t.Error(err)
+	}
+	ranges := testBurstRanges(t, repository)
+	if len(ranges) != 1 || ranges[0] != [2]TransactionId{1, 50} {
+		t.Error(ranges)
+	}
+	testCheckBursts(t, repository, 50, 50*51/2)
+
+	indexes, _ := filepath.Glob(filepath.Join(dir, "*.index"))
+	if len(indexes) != 1 {
+		t.Error(indexes)
+	}
+	if err := repository.Unlock(); err != nil {
+		t.Error(err)
+	}
+}
+
+// A MergeBurstRepository that wraps the BurstIds of another one, fails the
+// removals from the given one and the closes of its BurstWriters.
+type testFailMergeRepository struct {
+	*MemBurstRepository
+	removals, failRemoval int
+	failClose             bool
+}
+
+type testWrappedBurstId struct {
+	BurstId
+}
+
+func (r *testFailMergeRepository) Bursts() ([]BurstId, error) {
+	ids, err := r.MemBurstRepository.Bursts()
+	for i, id := range ids {
+		ids[i] = &testWrappedBurstId{id}
+	}
+	return ids, err
+}
+
+func (r *testFailMergeRepository) WriteBurst() (BurstWriter, error) {
+	writer, err := r.MemBurstRepository.WriteBurst()
+	if err != nil || !r.failClose {
+		return writer, err
+	}
+	return &testFailCloseBurstWriter{writer}, nil
+}
+
+func (r *testFailMergeRepository) RemoveBurst(id BurstId) error {
+	if r.removals++; r.removals >= r.failRemoval {
+		return errors.New("test")
+	}
+	return r.MemBurstRepository.RemoveBurst(id)
+}
+
+type testFailCloseBurstWriter struct {
+	BurstWriter
+}
+
+func (w *testFailCloseBurstWriter) Close() error {
+	w.BurstWriter.Close()
+	return errors.New("test")
+}
+
+func TestMergeBurstsRemoveError(t *testing.T) {
+
+	repository := &testFailMergeRepository{NewMemBurstRepository(), 0, 2, false}
+	testWriteBurst(t, repository, 1)
+	testWriteBurst(t, repository, 2)
+	testWriteBurst(t, repository, 3)
+
+	ids, _ := repository.Bursts()
+	removed, err := MergeBursts(context.Background(), repository, ids)
+	if removed != 1 || err == nil {
+		t.Error(removed, err)
+	}
+	if ranges := testBurstRanges(t, repository); len(ranges) != 3 || ranges[0] != [2]TransactionId{1, 3} {
+		t.Error(ranges)
+	}
+	testCheckBursts(t, repository, 3, 36)
+
+	repository.failRemoval = 10
+	if removed, err := CompactBursts(context.Background(), repository, 0); removed != 2 || err != nil {
+		t.Error(removed, err)
+	}
+	if ranges := testBurstRanges(t, repository); len(ranges) != 1 || ranges[0] != [2]TransactionId{1, 3} {
+		t.Error(ranges)
+	}
+	testCheckBursts(t, repository, 3, 36)
+}
+
+func TestMergeBurstsCloseError(t *testing.T) {
+
+	repository := &testFailMergeRepository{NewMemBurstRepository(), 0, 10, false}
+	testWriteBurst(t, repository, 1)
+	testWriteBurst(t, repository, 2)
+
+	repository.failClose = true
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ids, _ := repository.Bursts()
+	if removed, err := MergeBursts(ctx, repository, ids); removed != 0 || err != context.Canceled {
+		t.Error(removed, err)
+	}
+	if removed, err := MergeBursts(context.Background(), repository, ids); removed != 0 || err == nil || err == context.Canceled {
+		t.Error(removed, err)
+	}
+}
@@ -0,0 +1,64 @@
+package gobdb
+
+import (
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// It converts a Writer of an old type into an equivalent Writer of a newer
+// type.
+type Migration func(Writer) (Writer, error)
+
+var migrations = struct {
+	sync.RWMutex
+	m map[reflect.Type]Migration
+}{m: make(map[reflect.Type]Migration)}
+
+// It registers an old Writer type with the name it was encoded with in the
+// Bursts and Snapshots, and the Migration that converts its values.
+// BurstReaders and SnapshotReaders apply the Migrations transparently, again
+// and again while the resulting type has one too, so chains of renames are
+// supported. A Migration that leads back to a type already migrated is an
+// error when it is applied.
+// The old type must implement Writer to be decodable, but its Write() is not
+// invoked by ApplyBursts() or ApplySnapshot().
+// Like gob.RegisterName(), it panics if the name or the type are already
+// registered.
+func RegisterMigration(name string, old Writer, migration Migration) {
+	gob.RegisterName(name, old)
+	t := reflect.TypeOf(old)
+	migrations.Lock()
+	defer migrations.Unlock()
+	if _, ok := migrations.m[t]; ok {
+		panic("gobdb: registering duplicate migration for " + t.String())
+	}
+	migrations.m[t] = migration
+}
+
+// It applies the registered Migrations to a decoded Writer.
+func migrateWriter(writer Writer) (Writer, error) {
+	var seen map[reflect.Type]bool
+	for writer != nil {
+		t := reflect.TypeOf(writer)
+		migrations.RLock()
+		migration, ok := migrations.m[t]
+		migrations.RUnlock()
+		if !ok {
+			break
+		}
+		if seen[t] {
+			return nil, fmt.Errorf("gobdb: cycle of Migrations from %v", t)
+		}
+		if seen == nil {
+			seen = make(map[reflect.Type]bool)
+		}
+		seen[t] = true
+		var err error
+		if writer, err = migration(writer); err != nil {
+			return nil, err
+		}
+	}
+	return writer, nil
+}
@@ -0,0 +1,210 @@
+package gobdb
+
+import (
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// An old version of testWriter.
+type testWriterV0 struct {
+	Inc int
+}
+
+func (op *testWriterV0) Write(root Root) (interface{}, error) {
+	return nil, errors.New("testWriterV0 must be migrated")
+}
+
+// An even older version of testWriter.
+type testWriterV00 struct {
+	Value int
+}
+
+func (op *testWriterV00) Write(root Root) (interface{}, error) {
+	return nil, errors.New("testWriterV00 must be migrated")
+}
+
+// The current version of a Writer that was encoded as *gobdb.testAddWriter.
+type testAddWriterV0 struct {
+	Amount int
+}
+
+func (op *testAddWriterV0) Write(root Root) (interface{}, error) {
+	return nil, errors.New("testAddWriterV0 must be migrated")
+}
+
+// Two Writers whose Migrations lead to each other.
+type testCycleWriterA struct {
+}
+
+func (op *testCycleWriterA) Write(root Root) (interface{}, error) {
+	return nil, nil
+}
+
+type testCycleWriterB struct {
+}
+
+func (op *testCycleWriterB) Write(root Root) (interface{}, error) {
+	return nil, nil
+}
+
+func init() {
+	RegisterMigration("*gobdb.testAddWriter", &testAddWriterV0{}, func(w Writer) (Writer, error) {
+		return &testWriter{w.(*testAddWriterV0).Amount}, nil
+	})
+	RegisterMigration("gobdb.testCycleWriterA", &testCycleWriterA{}, func(w Writer) (Writer, error) {
+		return &testCycleWriterB{}, nil
+	})
+	RegisterMigration("gobdb.testCycleWriterB", &testCycleWriterB{}, func(w Writer) (Writer, error) {
+		return &testCycleWriterA{}, nil
+	})
+	RegisterMigration("gobdb.testWriterV0", &testWriterV0{}, func(w Writer) (Writer, error) {
+		return &testWriter{w.(*testWriterV0).Inc}, nil
+	})
+	RegisterMigration("gobdb.testWriterV00", &testWriterV00{}, func(w Writer) (Writer, error) {
+		return &testWriterV0{w.(*testWriterV00).Value}, nil
+	})
+}
+
+func TestMigrationApplyBursts(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+
+	wburst, err := repository.WriteBurst()
+	if err != nil {
+		t.Error(err)
+	}
+	if err := wburst.Write(Transaction{1, &testWriterV00{11}}); err != nil {
+		t.Error(err)
+	}
+	if err := wburst.Write(Transaction{2, &testWriterV0{12}}); err != nil {
+		t.Error(err)
+	}
+	if err := wburst.Write(Transaction{3, &testWriter{13}}); err != nil {
+		t.Error(err)
+	}
+	if err := wburst.Close(); err != nil {
+		t.Error(err)
+	}
+
+	root := &testRoot{0}
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	var id TransactionId
+	if err := ApplyBursts(root, 0, &id, bursts); err != nil {
+		t.Error(err)
+	}
+	if id != 3 {
+		t.Error(id)
+	}
+	if root.counter != 36 {
+		t.Error(root.counter)
+	}
+}
+
+func TestMigrationApplySnapshot(t *testing.T) {
+
+	repository := NewMemSnapshotRepository()
+
+	wsnapshot, err := repository.WriteSnapshot(2)
+	if err != nil {
+		t.Error(err)
+	}
+	if err := wsnapshot.Write(&testWriterV0{11}); err != nil {
+		t.Error(err)
+	}
+	if err := wsnapshot.Write(&testWriterV00{12}); err != nil {
+		t.Error(err)
+	}
+	if err := wsnapshot.Close(); err != nil {
+		t.Error(err)
+	}
+
+	root := &testRoot{}
+	snapshots, err := repository.Snapshots()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(snapshots) != 1 {
+		t.Fatal(len(snapshots))
+	}
+	if err := ApplySnapshot(root, snapshots[0]); err != nil {
+		t.Error(err)
+	}
+	if root.counter != 23 {
+		t.Error(root.counter)
+	}
+}
+
+func TestMigrationNone(t *testing.T) {
+
+	migrated, err := migrateWriter(&testWriter{1})
+	if err != nil {
+		t.Error(err)
+	}
+	if tw, ok := migrated.(*testWriter); !ok || tw.Increment != 1 {
+		t.Errorf("%#v", migrated)
+	}
+
+	migrated, err = migrateWriter(nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if migrated != nil {
+		t.Errorf("%#v", migrated)
+	}
+}
+
+// A Burst written by a version where the type of the Writer was named
+// testAddWriter, registered with gob.Register(&testAddWriter{}), with the
+// Transactions {1, &testAddWriter{10}} and {2, &testAddWriter{20}}.
+const testAddWriterBurst = "2a7f0301010b5472616e73616374696f6e01ff80000102010249640106000106577269746572011000000040ff80010101142a676f6264622e74657374416464577269746572ff810301010d7465737441646457726974657201ff820001010106416d6f756e74010400000007ff82030114000021ff80010201142a676f6264622e74657374416464577269746572ff820301280000"
+
+func TestMigrationRenamedType(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gobdb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data, err := hex.DecodeString(testAddWriterBurst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "burst-1-2.gobdb"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := &testRoot{}
+	bursts, err := NewDirBurstRepository(dir).Bursts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var id TransactionId
+	if err := ApplyBursts(root, 0, &id, bursts); err != nil {
+		t.Error(err)
+	}
+	if id != 2 {
+		t.Error(id)
+	}
+	if root.counter != 30 {
+		t.Error(root.counter)
+	}
+}
+
+func TestMigrationCycle(t *testing.T) {
+
+	migrated, err := migrateWriter(&testCycleWriterA{})
+	if err == nil {
+		t.Error(err)
+	}
+	if migrated != nil {
+		t.Errorf("%#v", migrated)
+	}
+}
@@ -24,12 +24,39 @@ func (bd *NumTransactionsBurstDispatcher) Write(transaction Transaction) (err er
 	return
 }
 
+// Implements HighWaterMarker.HighWaterMark(). It is zero if the other
+// BurstDispatcher does not implement it.
+func (bd *NumTransactionsBurstDispatcher) HighWaterMark() (TransactionId, error) {
+	if marker, ok := bd.dispatcher.(HighWaterMarker); ok {
+		return marker.HighWaterMark()
+	}
+	return 0, nil
+}
+
+// Implements LockBurstDispatcher.Lock(). It does nothing if the other
+// BurstDispatcher does not implement it.
+func (bd *NumTransactionsBurstDispatcher) Lock() error {
+	if locker, ok := bd.dispatcher.(LockBurstDispatcher); ok {
+		return locker.Lock()
+	}
+	return nil
+}
+
 // Implements BurstDispatcher.Rotate().
 func (bd *NumTransactionsBurstDispatcher) Rotate() (err error) {
 	bd.count = 0
 	return bd.dispatcher.Rotate()
 }
 
+// Implements Syncer.Sync(). It does nothing if the other BurstDispatcher does
+// not implement it.
+func (bd *NumTransactionsBurstDispatcher) Sync() error {
+	if syncer, ok := bd.dispatcher.(Syncer); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
+
 // Implements BurstDispatcher.Close().
 func (bd *NumTransactionsBurstDispatcher) Close() (err error) {
 	return bd.dispatcher.Close()
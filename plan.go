@@ -0,0 +1,262 @@
+package gobdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+// An optional interface of BurstIds and SnapshotIds.
+type Sizer interface {
+	// The number of bytes of the Burst or Snapshot.
+	Size() (int64, error)
+}
+
+// It returns the size of an id that implements Sizer, or zero.
+func sizeOf(id interface{}) int64 {
+	if sizer, ok := id.(Sizer); ok {
+		if size, err := sizer.Size(); err == nil {
+			return size
+		}
+	}
+	return 0
+}
+
+// It returns the minimal list of Bursts whose ranges cover the TransactionIds
+// after lastId until target, in the order they must be applied.
+// It assumes that the Bursts contain all the Transactions in their ranges, as
+// the ones written by the BurstDispatchers. If they can not be covered, it
+// returns an *ErrBurstGap.
+func PlanBursts(lastId, target TransactionId, burstIds []BurstId) ([]BurstId, error) {
+
+	ids := append([]BurstId(nil), burstIds...)
+	SortBursts(ids)
+
+	plan := []BurstId{}
+	next := lastId + 1
+	for i := 0; next <= target; {
+		var best BurstId
+		for ; i < len(ids) && ids[i].First() <= next; i++ {
+			if best == nil || ids[i].Last() > best.Last() {
+				best = ids[i]
+			}
+		}
+		if best == nil || best.Last() < next {
+			last := target
+			if i < len(ids) && ids[i].First() <= target {
+				last = ids[i].First() - 1
+			}
+			return nil, &ErrBurstGap{next, last}
+		}
+		plan = append(plan, best)
+		next = best.Last() + 1
+	}
+	return plan, nil
+}
+
+// The result of PlanRecovery().
+type RecoveryPlan struct {
+	// The Snapshot to apply first, or nil.
+	Snapshot SnapshotId
+	// The delta Snapshots to apply after the Snapshot, in order.
+	Deltas []SnapshotId
+	// The Bursts to apply after the Snapshot, in order.
+	Bursts []BurstId
+	// The last TransactionId applied before the Bursts.
+	LastId TransactionId
+	// The last TransactionId to apply.
+	Target TransactionId
+	// The estimated number of bytes to read, given by the ids that implement
+	// Sizer.
+	Bytes int64
+}
+
+// It explains the plan, one line per Snapshot or Burst.
+func (p *RecoveryPlan) String() string {
+	var buffer bytes.Buffer
+	if p.Snapshot != nil {
+		fmt.Fprintf(&buffer, "snapshot %d (%d bytes)\n", p.Snapshot.Id(), sizeOf(p.Snapshot))
+	}
+	for _, id := range p.Deltas {
+		var base TransactionId
+		if delta, ok := id.(DeltaSnapshotId); ok {
+			base = delta.Base()
+		}
+		fmt.Fprintf(&buffer, "delta snapshot %d-%d (%d bytes)\n", base, id.Id(), sizeOf(id))
+	}
+	for _, id := range p.Bursts {
+		fmt.Fprintf(&buffer, "burst %d-%d (%d bytes)\n", id.First(), id.Last(), sizeOf(id))
+	}
+	fmt.Fprintf(&buffer, "target %d (%d bytes)\n", p.Target, p.Bytes)
+	return buffer.String()
+}
+
+// It returns the chain of delta Snapshots that starts at the base and reaches
+// the furthest TransactionId not beyond the target, with the fewest delta
+// Snapshots among those, in the order they must be applied. The ids that do
+// not implement DeltaSnapshotId are ignored.
+func SnapshotChain(base, target TransactionId, deltaIds []SnapshotId) []SnapshotId {
+
+	deltas := make(map[TransactionId][]SnapshotId)
+	for _, id := range deltaIds {
+		if delta, ok := id.(DeltaSnapshotId); ok && delta.Base() != 0 && delta.Base() < delta.Id() && delta.Id() <= target {
+			deltas[delta.Base()] = append(deltas[delta.Base()], id)
+		}
+	}
+
+	chains := make(map[TransactionId][]SnapshotId)
+	var chainFrom func(TransactionId) []SnapshotId
+	chainFrom = func(base TransactionId) []SnapshotId {
+		if chain, ok := chains[base]; ok {
+			return chain
+		}
+		chain := []SnapshotId{}
+		for _, delta := range deltas[base] {
+			next := append([]SnapshotId{delta}, chainFrom(delta.Id())...)
+			if len(chain) == 0 {
+				chain = next
+				continue
+			}
+			last, nextLast := chain[len(chain)-1].Id(), next[len(next)-1].Id()
+			if nextLast > last || nextLast == last && len(next) < len(chain) {
+				chain = next
+			}
+		}
+		chains[base] = chain
+		return chain
+	}
+	return chainFrom(base)
+}
+
+// It plans the recovery of a Root until the target TransactionId, or the last
+// one available if it is zero. It chooses the newest Snapshot not after the
+// target whose next Transactions can be covered by SnapshotChain() and
+// PlanBursts(), or no Snapshot at all. The ids that implement DeltaSnapshotId
+// with a non zero Base() are only considered as part of the chains.
+func PlanRecovery(target TransactionId, snapshotIds []SnapshotId, burstIds []BurstId) (*RecoveryPlan, error) {
+
+	if target == 0 {
+		for _, id := range snapshotIds {
+			if id.Id() > target {
+				target = id.Id()
+			}
+		}
+		for _, id := range burstIds {
+			if id.Last() > target {
+				target = id.Last()
+			}
+		}
+	}
+
+	snapshots := make([]SnapshotId, 0, len(snapshotIds)+1)
+	for _, id := range snapshotIds {
+		if delta, ok := id.(DeltaSnapshotId); !ok || delta.Base() == 0 {
+			snapshots = append(snapshots, id)
+		}
+	}
+	SortSnapshots(snapshots)
+	snapshots = append(snapshots, nil)
+
+	var err error
+	for _, snapshot := range snapshots {
+		var lastId TransactionId
+		var deltas []SnapshotId
+		if snapshot != nil {
+			if lastId = snapshot.Id(); lastId > target {
+				continue
+			}
+			if deltas = SnapshotChain(lastId, target, snapshotIds); len(deltas) > 0 {
+				lastId = deltas[len(deltas)-1].Id()
+			}
+		}
+		var bursts []BurstId
+		if bursts, err = PlanBursts(lastId, target, burstIds); err != nil {
+			continue
+		}
+		plan := &RecoveryPlan{snapshot, deltas, bursts, lastId, target, 0}
+		if snapshot != nil {
+			plan.Bytes += sizeOf(snapshot)
+		}
+		for _, id := range deltas {
+			plan.Bytes += sizeOf(id)
+		}
+		for _, id := range bursts {
+			plan.Bytes += sizeOf(id)
+		}
+		return plan, nil
+	}
+	return nil, err
+}
+
+// It applies the Snapshots and the Bursts of a RecoveryPlan with
+// ApplySnapshotContext() and ApplyBurstsContext(), ignoring the Transactions
+// after the target. It returns the last TransactionId applied.
+// If the Root implements DirtyTracker, its modified state is cleared after the
+// Snapshots, so plan.LastId can be given to DefaultDatabase.SetSnapshotBase().
+func ApplyRecoveryPlan(ctx context.Context, root Root, plan *RecoveryPlan, nextLastId *TransactionId, progress func(Progress)) error {
+	*nextLastId = 0
+	if plan.Snapshot != nil {
+		snapshots := append([]SnapshotId{plan.Snapshot}, plan.Deltas...)
+		for _, snapshot := range snapshots {
+			if err := ApplySnapshotContext(ctx, root, snapshot, progress); err != nil {
+				return err
+			}
+			*nextLastId = snapshot.Id()
+		}
+		if tracker, ok := root.(DirtyTracker); ok {
+			tracker.ClearDirty()
+		}
+	}
+	ids := make([]BurstId, len(plan.Bursts))
+	for i, id := range plan.Bursts {
+		ids[i] = &limitBurstId{id, plan.Target}
+	}
+	return ApplyBurstsContext(ctx, root, plan.LastId, nextLastId, ids, progress)
+}
+
+// A BurstId whose Transactions after a TransactionId are ignored.
+type limitBurstId struct {
+	BurstId
+	target TransactionId
+}
+
+func (id *limitBurstId) Last() TransactionId {
+	if last := id.BurstId.Last(); last < id.target {
+		return last
+	}
+	return id.target
+}
+
+func (id *limitBurstId) Read() (BurstReader, error) {
+	reader, err := id.BurstId.Read()
+	if err != nil {
+		return nil, err
+	}
+	return &limitBurstReader{reader, id.target}, nil
+}
+
+func (id *limitBurstId) ReadFrom(from TransactionId) (BurstReader, error) {
+	reader, err := readBurst(id.BurstId, from)
+	if err != nil {
+		return nil, err
+	}
+	return &limitBurstReader{reader, id.target}, nil
+}
+
+type limitBurstReader struct {
+	BurstReader
+	target TransactionId
+}
+
+func (br *limitBurstReader) Read() (Transaction, error) {
+	transaction, err := br.BurstReader.Read()
+	if err == nil && transaction.Id > br.target {
+		return Transaction{}, io.EOF
+	}
+	return transaction, err
+}
+
+func (br *limitBurstReader) BytesRead() int64 {
+	return bytesRead(br.BurstReader)
+}
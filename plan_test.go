@@ -0,0 +1,167 @@
+package gobdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPlanBursts(t *testing.T) {
+
+	id1 := &memBurstId{1, 4, nil}
+	id2 := &memBurstId{2, 3, nil}
+	id3 := &memBurstId{3, 8, nil}
+	id4 := &memBurstId{5, 9, nil}
+	id5 := &memBurstId{9, 10, nil}
+	id6 := &memBurstId{12, 12, nil}
+	ids := []BurstId{id6, id5, id4, id3, id2, id1}
+
+	plan, err := PlanBursts(0, 10, ids)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(plan) != 3 || plan[0] != id1 || plan[1] != id4 || plan[2] != id5 {
+		t.Error(plan)
+	}
+
+	plan, err = PlanBursts(4, 9, ids)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(plan) != 1 || plan[0] != id4 {
+		t.Error(plan)
+	}
+
+	plan, err = PlanBursts(0, 12, ids)
+	if gap, ok := err.(*ErrBurstGap); !ok || gap.First != 11 || gap.Last != 11 {
+		t.Error(err)
+	}
+	if plan != nil {
+		t.Error(plan)
+	}
+
+	if ids[0] != id6 {
+		t.Error(ids)
+	}
+}
+
+func TestPlanRecovery(t *testing.T) {
+
+	bursts := NewMemBurstRepository()
+	testWriteBurst(t, bursts, 1, 2)
+	testWriteBurst(t, bursts, 1, 2, 3, 4)
+	testWriteBurst(t, bursts, 4, 5)
+	testWriteBurst(t, bursts, 7)
+
+	snapshots := NewMemSnapshotRepository()
+	for _, id := range []TransactionId{2, 6} {
+		wsnapshot, err := snapshots.WriteSnapshot(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := wsnapshot.Write(&testWriter{100 * int(id)}); err != nil {
+			t.Error(err)
+		}
+		if err := wsnapshot.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	burstIds, err := bursts.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	snapshotIds, err := snapshots.Snapshots()
+	if err != nil {
+		t.Error(err)
+	}
+
+	plan, err := PlanRecovery(0, snapshotIds, burstIds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Snapshot == nil || plan.Snapshot.Id() != 6 || plan.LastId != 6 || plan.Target != 7 {
+		t.Error(plan)
+	}
+	if len(plan.Bursts) != 1 || plan.Bursts[0].First() != 7 {
+		t.Error(plan)
+	}
+	if plan.Bytes <= 0 {
+		t.Error(plan.Bytes)
+	}
+
+	plan, err = PlanRecovery(4, snapshotIds, burstIds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Snapshot == nil || plan.Snapshot.Id() != 2 || plan.LastId != 2 || plan.Target != 4 {
+		t.Error(plan)
+	}
+	if len(plan.Bursts) != 1 || plan.Bursts[0].First() != 1 || plan.Bursts[0].Last() != 4 {
+		t.Error(plan)
+	}
+
+	root := &testRoot{}
+	var id TransactionId
+	if err := ApplyRecoveryPlan(context.Background(), root, plan, &id, nil); err != nil {
+		t.Error(err)
+	}
+	if id != 4 {
+		t.Error(id)
+	}
+	if root.counter != 227 {
+		t.Error(root.counter)
+	}
+
+	plan, err = PlanRecovery(5, snapshotIds, burstIds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Snapshot == nil || plan.Snapshot.Id() != 2 || len(plan.Bursts) != 2 {
+		t.Error(plan)
+	}
+	if s := plan.String(); s == "" {
+		t.Error(s)
+	}
+
+	root = &testRoot{}
+	if err := ApplyRecoveryPlan(context.Background(), root, plan, &id, nil); err != nil {
+		t.Error(err)
+	}
+	if id != 5 {
+		t.Error(id)
+	}
+	if root.counter != 242 {
+		t.Error(root.counter)
+	}
+}
+
+// A SnapshotId that does not implement DeltaSnapshotId.
+type testPlainSnapshotId struct {
+	id TransactionId
+}
+
+func (id *testPlainSnapshotId) Id() TransactionId {
+	return id.id
+}
+
+func (id *testPlainSnapshotId) Repository() SnapshotRepository {
+	return nil
+}
+
+func (id *testPlainSnapshotId) Read() (SnapshotReader, error) {
+	return nil, errors.New("testPlainSnapshotId can not be read")
+}
+
+func TestRecoveryPlanString(t *testing.T) {
+
+	plan := &RecoveryPlan{
+		Snapshot: &testPlainSnapshotId{2},
+		Deltas:   []SnapshotId{&testPlainSnapshotId{3}},
+		LastId:   3,
+		Target:   3,
+	}
+	if s := plan.String(); s != "snapshot 2 (0 bytes)\ndelta snapshot 0-3 (0 bytes)\ntarget 3 (0 bytes)\n" {
+		t.Error(s)
+	}
+}
@@ -0,0 +1,45 @@
+package gobdb
+
+import (
+	"io"
+)
+
+// The progress of ApplySnapshotContext() and ApplyBurstsContext().
+type Progress struct {
+	// The last TransactionId applied to the Root.
+	Id TransactionId
+	// The last TransactionId expected to be applied to the Root.
+	Target TransactionId
+	// The number of Bursts or Snapshots opened.
+	Opened int
+	// The number of bytes read by the BurstReaders or SnapshotReaders that
+	// implement ByteCounter.
+	Bytes int64
+}
+
+// An optional interface of BurstReaders and SnapshotReaders.
+type ByteCounter interface {
+	// The number of bytes read from the underlying storage.
+	BytesRead() int64
+}
+
+// It returns the number of bytes read by a reader that implements
+// ByteCounter.
+func bytesRead(reader interface{}) int64 {
+	if counter, ok := reader.(ByteCounter); ok {
+		return counter.BytesRead()
+	}
+	return 0
+}
+
+// An io.Reader that counts the bytes read.
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	r.count += int64(n)
+	return
+}
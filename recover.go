@@ -0,0 +1,44 @@
+package gobdb
+
+import (
+	"context"
+)
+
+// It recovers a Root with the newest Snapshots and Bursts of the repositories,
+// using PlanRecovery() and ApplyRecoveryPlan(). If the SnapshotRepository
+// implements DeltaSnapshotRepository, its delta Snapshots are considered too.
+// The SnapshotRepository is optional. It returns the plan and the last
+// TransactionId applied.
+func Recover(ctx context.Context, root Root, snapshots SnapshotRepository, bursts BurstRepository, nextLastId *TransactionId, progress func(Progress)) (*RecoveryPlan, error) {
+	*nextLastId = 0
+	plan, err := planRepositories(snapshots, bursts)
+	if err != nil {
+		return nil, err
+	}
+	return plan, ApplyRecoveryPlan(ctx, root, plan, nextLastId, progress)
+}
+
+// It plans the recovery of the newest state of the repositories, including the
+// delta Snapshots. The SnapshotRepository is optional.
+func planRepositories(snapshots SnapshotRepository, bursts BurstRepository) (*RecoveryPlan, error) {
+
+	var snapshotIds []SnapshotId
+	if snapshots != nil {
+		var err error
+		if snapshotIds, err = snapshots.Snapshots(); err != nil {
+			return nil, err
+		}
+		if deltas, ok := snapshots.(DeltaSnapshotRepository); ok {
+			deltaIds, err := deltas.DeltaSnapshots()
+			if err != nil {
+				return nil, err
+			}
+			snapshotIds = append(snapshotIds, deltaIds...)
+		}
+	}
+	burstIds, err := bursts.Bursts()
+	if err != nil {
+		return nil, err
+	}
+	return PlanRecovery(0, snapshotIds, burstIds)
+}
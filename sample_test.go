@@ -1,9 +1,5 @@
 package gobdb
 
-import (
-	"encoding/gob"
-)
-
 type testRoot struct {
 	counter int
 }
@@ -32,5 +28,5 @@ func testSnapshooter(root Root, write func(...Writer) error) error {
 }
 
 func init() {
-	gob.Register(&testWriter{})
+	RegisterWriter(&testWriter{})
 }
@@ -0,0 +1,128 @@
+package gobdb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// The user defined function that returns the shard of a Reader or a Writer,
+// from zero to the number of shards minus one. It must be deterministic.
+type ShardFunc func(interface{}) int
+
+// A Database and WriteDatabase whose model is split in several Roots, each one
+// with its own DefaultDatabase, sequence of TransactionIds and repositories.
+// Thread-safe: the operations on different shards run in parallel.
+type ShardedDatabase struct {
+	shard  ShardFunc
+	shards []*shardedDatabaseShard
+}
+
+type shardedDatabaseShard struct {
+	mutex    sync.RWMutex
+	database *DefaultDatabase
+}
+
+// New instance, with one DefaultDatabase per shard.
+func NewShardedDatabase(shard ShardFunc, databases ...*DefaultDatabase) *ShardedDatabase {
+	shards := make([]*shardedDatabaseShard, len(databases))
+	for i, database := range databases {
+		shards[i] = &shardedDatabaseShard{database: database}
+	}
+	return &ShardedDatabase{shard, shards}
+}
+
+// The number of shards.
+func (db *ShardedDatabase) Shards() int {
+	return len(db.shards)
+}
+
+// Implements Database.Read(). It panics if the shard is out of range.
+func (db *ShardedDatabase) Read(reader Reader) interface{} {
+	i := db.shard(reader)
+	if i < 0 || i >= len(db.shards) {
+		panic(fmt.Sprintf("gobdb: shard %d of Reader out of range", i))
+	}
+	shard := db.shards[i]
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+	return shard.database.Read(reader)
+}
+
+// Implements WriteDatabase.Write(). The first error is not nil if the shard is
+// out of range.
+func (db *ShardedDatabase) Write(writer Writer) (interface{}, error, error) {
+	i := db.shard(writer)
+	if i < 0 || i >= len(db.shards) {
+		return nil, fmt.Errorf("gobdb: shard %d of Writer out of range", i), nil
+	}
+	shard := db.shards[i]
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	return shard.database.Write(writer)
+}
+
+// It takes a Snapshot of every shard in parallel, each one in its own
+// WriteSnapshotRepository. It returns the first error.
+func (db *ShardedDatabase) TakeSnapshots(snapshooter Snapshooter, repositories []WriteSnapshotRepository) error {
+	if len(repositories) != len(db.shards) {
+		return fmt.Errorf("gobdb: %d WriteSnapshotRepositories for %d shards", len(repositories), len(db.shards))
+	}
+	return parallel(len(db.shards), func(i int) error {
+		shard := db.shards[i]
+		shard.mutex.Lock()
+		defer shard.mutex.Unlock()
+		return shard.database.TakeSnapshot(snapshooter, repositories[i])
+	})
+}
+
+// It recovers every Root in parallel with Recover(), each one from its own
+// repositories. The SnapshotRepositories are optional. It returns the last
+// TransactionId applied to each Root and the first error.
+func RecoverShards(ctx context.Context, roots []Root, snapshots []SnapshotRepository, bursts []BurstRepository) ([]TransactionId, error) {
+	if len(snapshots) != len(roots) || len(bursts) != len(roots) {
+		return nil, fmt.Errorf("gobdb: %d SnapshotRepositories and %d BurstRepositories for %d shards", len(snapshots), len(bursts), len(roots))
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ids := make([]TransactionId, len(roots))
+	var (
+		once  sync.Once
+		first error
+	)
+	parallel(len(roots), func(i int) error {
+		_, err := Recover(ctx, roots[i], snapshots[i], bursts[i], &ids[i], nil)
+		if err != nil {
+			once.Do(func() {
+				first = err
+				cancel()
+			})
+		}
+		return err
+	})
+	return ids, first
+}
+
+// It invokes the function with every index in parallel and returns the first
+// error that happens.
+func parallel(n int, f func(int) error) (err error) {
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+	)
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			if e := f(i); e != nil {
+				mutex.Lock()
+				if err == nil {
+					err = e
+				}
+				mutex.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	return
+}
@@ -0,0 +1,140 @@
+package gobdb
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// A Reader of a shard of testRoots.
+type testShardReader struct {
+	Shard int
+}
+
+func (op *testShardReader) Read(root Root) interface{} {
+	return root.(*testRoot).counter
+}
+
+// A Writer of a shard of testRoots.
+type testShardWriter struct {
+	Shard     int
+	Increment int
+}
+
+func (op *testShardWriter) Write(root Root) (interface{}, error) {
+	r := root.(*testRoot)
+	r.counter += op.Increment
+	return r.counter, nil
+}
+
+func testShardFunc(op interface{}) int {
+	switch op := op.(type) {
+	case *testShardReader:
+		return op.Shard
+	case *testShardWriter:
+		return op.Shard
+	}
+	return -1
+}
+
+func init() {
+	RegisterWriter(&testShardWriter{})
+}
+
+func TestShardedDatabaseInterface(t *testing.T) {
+
+	var i interface{} = NewShardedDatabase(testShardFunc)
+	if _, ok := i.(Database); !ok {
+		t.Error(i)
+	}
+	if _, ok := i.(WriteDatabase); !ok {
+		t.Error(i)
+	}
+}
+
+func TestShardedDatabase(t *testing.T) {
+
+	const n = 3
+	bursts := make([]BurstRepository, n)
+	snapshots := make([]SnapshotRepository, n)
+	wsnapshots := make([]WriteSnapshotRepository, n)
+	dispatchers := make([]BurstDispatcher, n)
+	databases := make([]*DefaultDatabase, n)
+	for i := 0; i < n; i++ {
+		burstRepository := NewMemBurstRepository()
+		snapshotRepository := NewMemSnapshotRepository()
+		bursts[i], snapshots[i], wsnapshots[i] = burstRepository, snapshotRepository, snapshotRepository
+		dispatchers[i] = NewDefaultBurstDispatcher(burstRepository)
+		databases[i] = NewDefaultDatabase(&testRoot{}, 0, dispatchers[i])
+	}
+	database := NewShardedDatabase(testShardFunc, databases...)
+	if database.Shards() != n {
+		t.Error(database.Shards())
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err1, err2 := database.Write(&testShardWriter{i % n, i}); err1 != nil || err2 != nil {
+				t.Error(err1, err2)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if err := database.TakeSnapshots(testSnapshooter, wsnapshots); err != nil {
+		t.Error(err)
+	}
+	if _, err1, err2 := database.Write(&testShardWriter{1, 100}); err1 != nil || err2 != nil {
+		t.Error(err1, err2)
+	}
+	if _, err1, _ := database.Write(&testShardWriter{n, 1}); err1 == nil {
+		t.Error(err1)
+	}
+
+	expected := []int{18, 112, 15}
+	for i := 0; i < n; i++ {
+		if value := database.Read(&testShardReader{i}); value != expected[i] {
+			t.Error(i, value)
+		}
+		if err := dispatchers[i].Close(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	roots := []Root{&testRoot{}, &testRoot{}, &testRoot{}}
+	ids, err := RecoverShards(context.Background(), roots, snapshots, bursts)
+	if err != nil {
+		t.Error(err)
+	}
+	for i := 0; i < n; i++ {
+		if counter := roots[i].(*testRoot).counter; counter != expected[i] {
+			t.Error(i, counter)
+		}
+	}
+	if len(ids) != n || ids[0] != 4 || ids[1] != 4 || ids[2] != 3 {
+		t.Error(ids)
+	}
+}
+
+func TestShardedDatabaseOutOfRange(t *testing.T) {
+
+	root := &testRoot{}
+	database := NewShardedDatabase(testShardFunc, NewDefaultDatabase(root, 0, nil))
+
+	if _, err1, err2 := database.Write(&testShardWriter{1, 1}); err1 == nil || err2 != nil {
+		t.Error(err1, err2)
+	}
+	if root.counter != 0 {
+		t.Error(root.counter)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error(r)
+		}
+	}()
+	database.Read(&testShardReader{-1})
+}
@@ -40,3 +40,25 @@ type WriteSnapshotRepository interface {
 	// Get a SnapshotWriter of a Snapshot.
 	WriteSnapshot(TransactionId) (SnapshotWriter, error)
 }
+
+// A delta Snapshot is a gob stream of Writers. To apply these Writers in
+// sequence to a Root recovered until its Base() must yield the same result
+// than to apply the Writers of all Transactions until its Id().
+type DeltaSnapshotId interface {
+	SnapshotId
+	// The last transaction of the previous Snapshot of the chain, zero if it
+	// is a full Snapshot.
+	Base() TransactionId
+}
+
+// A container that can read delta Snapshots.
+type DeltaSnapshotRepository interface {
+	// List of all delta Snapshots, they implement DeltaSnapshotId.
+	DeltaSnapshots() ([]SnapshotId, error)
+}
+
+// A container that can write delta Snapshots.
+type WriteDeltaSnapshotRepository interface {
+	// Get a SnapshotWriter of a delta Snapshot from a base one.
+	WriteDeltaSnapshot(base, id TransactionId) (SnapshotWriter, error)
+}
@@ -12,9 +12,8 @@ func (s burstIdSlice) Len() int {
 }
 
 func (s burstIdSlice) Less(i, j int) bool {
-	diff := s[i].First() - s[j].First()
-	if diff != 0 {
-		return diff < 0
+	if first1, first2 := s[i].First(), s[j].First(); first1 != first2 {
+		return first1 < first2
 	}
 	return s[i].Last() > s[j].Last()
 }
@@ -0,0 +1,34 @@
+package gobdb
+
+// A BurstRepository that lists the Bursts of several ones. The Bursts with the
+// same range in several repositories are listed once, from the first one. The
+// BurstIds are the ones of the backing repositories, so they are read from and
+// report them as their Repository(). Thread-safe if they are.
+type UnionBurstRepository struct {
+	repositories []BurstRepository
+}
+
+// New instance.
+func NewUnionBurstRepository(repositories ...BurstRepository) *UnionBurstRepository {
+	return &UnionBurstRepository{repositories}
+}
+
+// Implements BurstRepository.Bursts().
+func (r *UnionBurstRepository) Bursts() ([]BurstId, error) {
+	ids := []BurstId{}
+	ranges := make(map[[2]TransactionId]bool)
+	for _, repository := range r.repositories {
+		bursts, err := repository.Bursts()
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range bursts {
+			key := [2]TransactionId{id.First(), id.Last()}
+			if !ranges[key] {
+				ranges[key] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,54 @@
+package gobdb
+
+import (
+	"testing"
+)
+
+func TestUnionBurstRepositoryInterface(t *testing.T) {
+
+	var i interface{} = NewUnionBurstRepository()
+	if _, ok := i.(BurstRepository); !ok {
+		t.Error(i)
+	}
+}
+
+func TestUnionBurstRepository(t *testing.T) {
+
+	recent := NewMemBurstRepository()
+	testWriteBurst(t, recent, 3, 4)
+	testWriteBurst(t, recent, 5)
+	archive := NewMemBurstRepository()
+	testWriteBurst(t, archive, 1, 2)
+	testWriteBurst(t, archive, 3, 4)
+
+	repository := NewUnionBurstRepository(recent, archive)
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(bursts) != 3 {
+		t.Fatal(bursts)
+	}
+	SortBursts(bursts)
+	if bursts[0].First() != 1 || bursts[0].Repository() != archive {
+		t.Error(bursts[0])
+	}
+	if bursts[1].First() != 3 || bursts[1].Repository() != recent {
+		t.Error(bursts[1])
+	}
+	if bursts[2].First() != 5 || bursts[2].Repository() != recent {
+		t.Error(bursts[2])
+	}
+
+	root := &testRoot{}
+	var id TransactionId
+	if err := ApplyBursts(root, 0, &id, bursts); err != nil {
+		t.Error(err)
+	}
+	if id != 5 {
+		t.Error(id)
+	}
+	if root.counter != 65 {
+		t.Error(root.counter)
+	}
+}
@@ -0,0 +1,56 @@
+package gobdb
+
+// A SnapshotRepository and DeltaSnapshotRepository that lists the Snapshots of
+// several ones. The Snapshots with the same TransactionId, and the same base
+// if they are delta ones, in several repositories are listed once, from the
+// first one. The SnapshotIds are the ones of the backing repositories, so they
+// are read from and report them as their Repository(). Thread-safe if they
+// are.
+type UnionSnapshotRepository struct {
+	repositories []SnapshotRepository
+}
+
+// New instance.
+func NewUnionSnapshotRepository(repositories ...SnapshotRepository) *UnionSnapshotRepository {
+	return &UnionSnapshotRepository{repositories}
+}
+
+// Implements SnapshotRepository.Snapshots().
+func (r *UnionSnapshotRepository) Snapshots() ([]SnapshotId, error) {
+	return r.snapshots(func(repository SnapshotRepository) ([]SnapshotId, error) {
+		return repository.Snapshots()
+	})
+}
+
+// Implements DeltaSnapshotRepository.DeltaSnapshots(). The repositories that
+// do not implement DeltaSnapshotRepository are skipped.
+func (r *UnionSnapshotRepository) DeltaSnapshots() ([]SnapshotId, error) {
+	return r.snapshots(func(repository SnapshotRepository) ([]SnapshotId, error) {
+		if deltas, ok := repository.(DeltaSnapshotRepository); ok {
+			return deltas.DeltaSnapshots()
+		}
+		return nil, nil
+	})
+}
+
+func (r *UnionSnapshotRepository) snapshots(list func(SnapshotRepository) ([]SnapshotId, error)) ([]SnapshotId, error) {
+	ids := []SnapshotId{}
+	keys := make(map[[2]TransactionId]bool)
+	for _, repository := range r.repositories {
+		snapshots, err := list(repository)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range snapshots {
+			key := [2]TransactionId{0, id.Id()}
+			if delta, ok := id.(DeltaSnapshotId); ok {
+				key[0] = delta.Base()
+			}
+			if !keys[key] {
+				keys[key] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,65 @@
+package gobdb
+
+import (
+	"testing"
+)
+
+func TestUnionSnapshotRepositoryInterface(t *testing.T) {
+
+	var i interface{} = NewUnionSnapshotRepository()
+	if _, ok := i.(SnapshotRepository); !ok {
+		t.Error(i)
+	}
+	if _, ok := i.(DeltaSnapshotRepository); !ok {
+		t.Error(i)
+	}
+}
+
+func TestUnionSnapshotRepository(t *testing.T) {
+
+	recent := NewMemSnapshotRepository()
+	archive := NewMemSnapshotRepository()
+	for _, write := range []struct {
+		repository *MemSnapshotRepository
+		base, id   TransactionId
+	}{{recent, 0, 4}, {recent, 4, 6}, {archive, 0, 2}, {archive, 0, 4}, {archive, 4, 6}, {archive, 4, 5}} {
+		wsnapshot, err := write.repository.WriteDeltaSnapshot(write.base, write.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := wsnapshot.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	repository := NewUnionSnapshotRepository(recent, archive)
+	snapshots, err := repository.Snapshots()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatal(snapshots)
+	}
+	SortSnapshots(snapshots)
+	if snapshots[0].Id() != 4 || snapshots[0].Repository() != recent {
+		t.Error(snapshots[0])
+	}
+	if snapshots[1].Id() != 2 || snapshots[1].Repository() != archive {
+		t.Error(snapshots[1])
+	}
+
+	deltas, err := repository.DeltaSnapshots()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(deltas) != 2 {
+		t.Fatal(deltas)
+	}
+	SortSnapshots(deltas)
+	if deltas[0].Id() != 6 || deltas[0].Repository() != recent {
+		t.Error(deltas[0])
+	}
+	if deltas[1].Id() != 5 || deltas[1].Repository() != archive {
+		t.Error(deltas[1])
+	}
+}
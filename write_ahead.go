@@ -0,0 +1,23 @@
+package gobdb
+
+// The Writer logged by a DefaultDatabase in write-ahead mode right after a
+// Transaction whose Writer failed. ApplyBursts() ignores the error of the
+// aborted Transaction when it is followed by this one.
+type AbortedTransaction struct {
+	Id TransactionId
+}
+
+// Implements Writer.Write(). It does nothing.
+func (op *AbortedTransaction) Write(root Root) (interface{}, error) {
+	return nil, nil
+}
+
+// An optional interface of BurstDispatchers and BurstWriters that can make the
+// Transactions written durable. Sync() returns once they would be recovered after a crash.
+type Syncer interface {
+	Sync() error
+}
+
+func init() {
+	RegisterWriter(&AbortedTransaction{})
+}
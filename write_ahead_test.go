@@ -0,0 +1,188 @@
+package gobdb
+
+import (
+	"errors"
+	"testing"
+)
+
+// A Writer that fails if the counter would exceed the limit.
+type testLimitWriter struct {
+	Increment, Limit int
+}
+
+func (op *testLimitWriter) Write(root Root) (interface{}, error) {
+	r := root.(*testRoot)
+	if r.counter+op.Increment > op.Limit {
+		return nil, errors.New("limit exceeded")
+	}
+	r.counter += op.Increment
+	return r.counter, nil
+}
+
+func init() {
+	RegisterWriter(&testLimitWriter{})
+}
+
+// A BurstDispatcher that always fails.
+type testFailBurstDispatcher struct {
+}
+
+func (bd *testFailBurstDispatcher) Write(transaction Transaction) error {
+	return errors.New("test")
+}
+
+func (bd *testFailBurstDispatcher) Rotate() error {
+	return nil
+}
+
+func (bd *testFailBurstDispatcher) Close() error {
+	return nil
+}
+
+func TestDefaultDatabaseWriteAhead(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	dispatcher := NewAsyncBurstDispatcher(1, NewDefaultBurstDispatcher(repository))
+	root := &testRoot{}
+	database := NewDefaultDatabase(root, 0, dispatcher)
+	database.SetWriteAhead(true)
+
+	if value, err1, err2 := database.Write(&testLimitWriter{3, 5}); value != 3 || err1 != nil || err2 != nil {
+		t.Error(value, err1, err2)
+	}
+	if _, err1, err2 := database.Write(&testLimitWriter{3, 5}); err1 == nil || err2 != nil {
+		t.Error(err1, err2)
+	}
+	if value, err1, err2 := database.Write(&testWriter{1}); value != 4 || err1 != nil || err2 != nil {
+		t.Error(value, err1, err2)
+	}
+	if err := dispatcher.Close(); err != nil {
+		t.Error(err)
+	}
+
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(bursts) != 4 {
+		t.Fatal(bursts)
+	}
+	SortBursts(bursts)
+	for i, burst := range bursts {
+		id := TransactionId(i + 1)
+		if burst.First() != id || burst.Last() != id {
+			t.Error(burst)
+		}
+		reader, err := burst.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		transaction, err := reader.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if transaction.Id != id {
+			t.Error(transaction.Id)
+		}
+		if op, ok := transaction.Writer.(*AbortedTransaction); ok != (id == 3) || ok && op.Id != 2 {
+			t.Error(transaction)
+		}
+		if err := reader.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	root = &testRoot{}
+	var id TransactionId
+	if err := ApplyBursts(root, 0, &id, bursts); err != nil {
+		t.Error(err)
+	}
+	if id != 4 || root.counter != 4 {
+		t.Error(id, root.counter)
+	}
+}
+
+func TestDefaultDatabaseWriteAheadDispatcherError(t *testing.T) {
+
+	root := &testRoot{}
+	database := NewDefaultDatabase(root, 0, &testFailBurstDispatcher{})
+	database.SetWriteAhead(true)
+	if value, err1, err2 := database.Write(&testWriter{1}); value != nil || err1 != nil || err2 == nil {
+		t.Error(value, err1, err2)
+	}
+	if root.counter != 0 {
+		t.Error(root.counter)
+	}
+}
+
+func TestApplyBurstsAborted(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	for _, transactions := range [][]Transaction{{{1, &testWriter{1}}, {2, &testLimitWriter{3, 2}}, {3, &AbortedTransaction{2}}}, {{4, &testWriter{1}}}} {
+		wburst, err := repository.WriteBurst()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, transaction := range transactions {
+			if err := wburst.Write(transaction); err != nil {
+				t.Error(err)
+			}
+		}
+		if err := wburst.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	SortBursts(bursts)
+
+	root := &testRoot{}
+	var id TransactionId
+	if err := ApplyBursts(root, 0, &id, bursts[:1]); err != nil {
+		t.Error(err)
+	}
+	if id != 3 || root.counter != 1 {
+		t.Error(id, root.counter)
+	}
+
+	root = &testRoot{}
+	if err := ApplyBursts(root, 0, &id, bursts); err != nil {
+		t.Error(err)
+	}
+	if id != 4 || root.counter != 2 {
+		t.Error(id, root.counter)
+	}
+}
+
+func TestApplyBurstsNotAborted(t *testing.T) {
+
+	repository := NewMemBurstRepository()
+	wburst, err := repository.WriteBurst()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, transaction := range []Transaction{{1, &testWriter{1}}, {2, &testLimitWriter{3, 2}}, {3, &testWriter{1}}} {
+		if err := wburst.Write(transaction); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := wburst.Close(); err != nil {
+		t.Error(err)
+	}
+
+	bursts, err := repository.Bursts()
+	if err != nil {
+		t.Error(err)
+	}
+	root := &testRoot{}
+	var id TransactionId
+	if err := ApplyBursts(root, 0, &id, bursts); err == nil {
+		t.Error(err)
+	}
+	if id != 1 || root.counter != 1 {
+		t.Error(id, root.counter)
+	}
+}
@@ -0,0 +1,57 @@
+package gobdb
+
+import (
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// The error returned as the first one by DefaultDatabase.Write() when the type
+// of the Writer has not been registered with RegisterWriter() or it is not gob
+// encodable. The Root object has not been modified.
+type ErrUnregisteredWriter struct {
+	Writer Writer
+	Err    error
+}
+
+func (e *ErrUnregisteredWriter) Error() string {
+	return fmt.Sprintf("gobdb: Writer %T is not gob encodable: %v", e.Writer, e.Err)
+}
+
+var writers = struct {
+	sync.RWMutex
+	m map[reflect.Type]bool
+}{m: make(map[reflect.Type]bool)}
+
+// It registers the type of a Writer with gob.Register() and adds it to the
+// list of accepted Writers.
+func RegisterWriter(writer Writer) {
+	gob.Register(writer)
+	writers.Lock()
+	defer writers.Unlock()
+	writers.m[reflect.TypeOf(writer)] = true
+}
+
+// It returns whether the type of the Writer has been registered with
+// RegisterWriter().
+func isRegisteredWriter(writer Writer) bool {
+	writers.RLock()
+	defer writers.RUnlock()
+	return writers.m[reflect.TypeOf(writer)]
+}
+
+// The types registered with RegisterWriter(), sorted by name.
+func RegisteredWriters() []reflect.Type {
+	writers.RLock()
+	defer writers.RUnlock()
+	types := make([]reflect.Type, 0, len(writers.m))
+	for t := range writers.m {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].String() < types[j].String()
+	})
+	return types
+}
@@ -0,0 +1,22 @@
+package gobdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegisteredWriters(t *testing.T) {
+
+	found := false
+	for _, typ := range RegisteredWriters() {
+		if typ == reflect.TypeOf(&testWriter{}) {
+			found = true
+		}
+		if typ == reflect.TypeOf(&testUnregisteredWriter{}) {
+			t.Error(typ)
+		}
+	}
+	if !found {
+		t.Error(RegisteredWriters())
+	}
+}